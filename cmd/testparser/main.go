@@ -2,21 +2,28 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/maxgreen01/go-test-parser/internal/config"
 	"github.com/maxgreen01/go-test-parser/internal/filewriter"
 	"github.com/maxgreen01/go-test-parser/internal/parsercommands"
 	"github.com/maxgreen01/go-test-parser/pkg/parser"
+	"github.com/maxgreen01/go-test-parser/pkg/progress"
+	"github.com/maxgreen01/go-test-parser/pkg/testcase"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
 	slogmulti "github.com/samber/slog-multi"
 )
 
@@ -41,9 +48,12 @@ func main() {
 		// Validate and apply global flags
 		applyGlobals(&opts)
 
-		task, ok := command.(parser.Task)
+		// Allow the command to be interrupted gracefully
+		opts.Context = handleInterrupts()
+
+		cmd, ok := command.(parsercommands.Command)
 		if !ok {
-			slog.Error("Command does not implement the Task interface")
+			slog.Error("Command does not implement the Command interface")
 			os.Exit(1)
 		}
 
@@ -56,7 +66,11 @@ func main() {
 
 		// Actually execute the command (which starts the parser)
 		if err := command.Execute(args); err != nil {
-			slog.Error("Error parsing project", "err", err, "task", task.Name(), "project", opts.ProjectDir)
+			if opts.Context.Err() != nil {
+				slog.Warn("Stopped early because the program was interrupted", "err", err, "task", cmd.Name(), "project", opts.ProjectDir)
+				os.Exit(130) // Conventional exit code for programs terminated by Ctrl-C
+			}
+			slog.Error("Error parsing project", "err", err, "task", cmd.Name(), "project", opts.ProjectDir)
 			os.Exit(1)
 		}
 
@@ -74,6 +88,30 @@ func main() {
 	}
 }
 
+// Listen for interrupt signals (e.g. Ctrl-C), returning a context that is canceled by the first signal so the parser
+// can stop starting new work and report partial results. A second signal exits immediately, after restoring any
+// source files that are modified by in-progress refactorings.
+func handleInterrupts() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		slog.Warn("Interrupted! Finishing in-progress files and reporting partial results (interrupt again to exit immediately)")
+		cancel()
+
+		<-signals
+		slog.Warn("Interrupted again, exiting immediately")
+		if restored := testcase.RestoreModifiedFiles(); restored > 0 {
+			slog.Info("Restored files modified by in-progress refactorings", "files", restored)
+		}
+		os.Exit(130)
+	}()
+
+	return ctx
+}
+
 // Validate (in-place) and apply global flags such as logging level and color output
 func applyGlobals(opts *config.GlobalOptions) {
 	//
@@ -102,9 +140,30 @@ func applyGlobals(opts *config.GlobalOptions) {
 	}
 	opts.ProjectDir = absPath
 
+	// Validate the split mode, treating `splitByDir` as an alias for splitting by directory.
+	// Allowed options are handled by the `choice` tag in the struct definition.
+	opts.SplitBy = strings.ToLower(strings.TrimSpace(opts.SplitBy))
+	if opts.SplitByDir {
+		if opts.SplitBy != "none" && opts.SplitBy != "dir" {
+			fmt.Fprintf(os.Stderr, "The splitByDir option cannot be combined with --splitBy=%s\n", opts.SplitBy)
+			os.Exit(1)
+		}
+		opts.SplitBy = "dir"
+	}
+
 	// Validate log level. Allowed options are handled by the `choice` tag in the struct definition.
 	opts.LogLevel = strings.ToLower(strings.TrimSpace(opts.LogLevel))
 
+	// Validate the progress mode, using the live display only if `stderr` is a terminal when set to "auto".
+	// Allowed options are handled by the `choice` tag in the struct definition.
+	progressMode := progress.ModeFromString(strings.TrimSpace(opts.ProgressMode))
+	if progressMode == progress.ModeAuto {
+		progressMode = progress.ModeLog
+		if isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd()) {
+			progressMode = progress.ModeLive
+		}
+	}
+
 	// Validate and resolve the output path, if specified. Additional validation and processing is done by FileWriter.
 	opts.OutputPath = strings.Trim(opts.OutputPath, "\t\n\v\f\r \"") // Trim whitespace and quotes
 	if opts.OutputPath != "" {
@@ -116,12 +175,57 @@ func applyGlobals(opts *config.GlobalOptions) {
 		opts.OutputPath = absPath
 	}
 
-	// Validate the number of threads used if splitting by directory
+	// Resolve the cache directory, if specified
+	opts.CacheDir = strings.Trim(opts.CacheDir, "\t\n\v\f\r \"") // Trim whitespace and quotes
+	if opts.CacheDir != "" {
+		absPath, err := filepath.Abs(opts.CacheDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving absolute path for cache directory %q: %v\n", opts.CacheDir, err)
+			os.Exit(1)
+		}
+		opts.CacheDir = absPath
+	}
+
+	// Validate the filter patterns
+	opts.TestPattern = strings.TrimSpace(opts.TestPattern)
+	filter, err := parser.NewFilter(opts.Include, opts.Exclude, opts.TestPattern)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid filter: %v\n", err)
+		os.Exit(1)
+	}
+	opts.Filter = filter
+
+	// Validate the build tags and target platforms, combining them into the build configurations used to load packages
+	buildConfigs, err := parser.BuildMatrix(opts.Tags, opts.Platforms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid build configuration: %v\n", err)
+		os.Exit(1)
+	}
+	opts.BuildConfigs = buildConfigs
+
+	// Validate the number of threads used for parsing
 	if opts.Threads < 1 {
 		fmt.Fprintf(os.Stderr, "Invalid number of threads %d specified, must be at least 1\n", opts.Threads)
 		os.Exit(1)
 	}
 
+	// Validate the batch size and memory limit, loading packages in batches if only a memory limit is specified.
+	// The memory limit also makes the garbage collector work harder as memory usage approaches it.
+	if opts.BatchSize < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid batch size %d specified, must be at least 0\n", opts.BatchSize)
+		os.Exit(1)
+	}
+	if opts.MemoryLimit < 0 {
+		fmt.Fprintf(os.Stderr, "Invalid memory limit %d specified, must be at least 0\n", opts.MemoryLimit)
+		os.Exit(1)
+	}
+	if opts.MemoryLimit > 0 {
+		if opts.BatchSize == 0 {
+			opts.BatchSize = parser.DefaultBatchSize
+		}
+		debug.SetMemoryLimit(int64(opts.MemoryLimit) << 20)
+	}
+
 	// Map log level string value to a `slog.Level`
 	var level slog.Level
 	switch opts.LogLevel {
@@ -146,9 +250,13 @@ func applyGlobals(opts *config.GlobalOptions) {
 
 	var handlers []slog.Handler
 
-	// Crate `stderr` handler (with color output support)
+	// Create the progress tracker, which shares `stderr` with the logs
+	stderr := colorable.NewColorableStderr()
+	opts.Progress = progress.NewTracker(progressMode, stderr)
+
+	// Crate `stderr` handler (with color output support), which is wrapped so logs don't overlap the live progress display
 	handlers = append(handlers,
-		tint.NewHandler(colorable.NewColorableStderr(), &tint.Options{
+		tint.NewHandler(opts.Progress.Writer(stderr), &tint.Options{
 			Level:      level,
 			TimeFormat: time.DateTime,
 			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
@@ -9,6 +9,7 @@ require (
 	github.com/jessevdk/go-flags v1.6.1
 	github.com/lmittmann/tint v1.1.1
 	github.com/mattn/go-colorable v0.1.14
+	github.com/mattn/go-isatty v0.0.20
 	github.com/samber/slog-multi v1.4.0
 	golang.org/x/sync v0.14.0
 	golang.org/x/tools v0.33.0
@@ -16,7 +17,6 @@ require (
 
 require (
 	github.com/google/go-cmp v0.7.0 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/samber/lo v1.49.1 // indirect
 	golang.org/x/exp/typeparams v0.0.0-20230203172020-98cc5a0785f9 // indirect
 	golang.org/x/mod v0.24.0 // indirect
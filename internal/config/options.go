@@ -1,12 +1,48 @@
 package config
 
+import (
+	"context"
+
+	"github.com/maxgreen01/go-test-parser/pkg/parser"
+	"github.com/maxgreen01/go-test-parser/pkg/progress"
+)
+
 // Definitions for global command-line flags used across the entire application
 type GlobalOptions struct {
-	ProjectDir   string `long:"project" short:"p" description:"Path to the Go project directory to be parsed"`
-	OutputPath   string `long:"output" short:"o" description:"Path to report output file"`
-	AppendOutput bool   `long:"append" description:"Whether to append to the output file instead of overwriting it if the file already exists"`
-	SplitByDir   bool   `long:"splitByDir" description:"Whether to parse each top-level directory separately (ignoring top-level Go files)"`
-	Threads      int    `long:"threads" description:"The number of concurrent threads to use for parsing (only when splitting by directory)" default:"4"`
+	ProjectDir        string   `long:"project" short:"p" description:"Path to the Go project directory to be parsed"`
+	OutputPath        string   `long:"output" short:"o" description:"Path to report output file"`
+	AppendOutput      bool     `long:"append" description:"Whether to append to the output file instead of overwriting it if the file already exists"`
+	SplitBy           string   `long:"splitBy" description:"How to split the project into units that are parsed separately, each with its own report" choice:"none" choice:"dir" choice:"module" choice:"package" default:"none"`
+	SplitByDir        bool     `long:"splitByDir" description:"Alias for --splitBy=dir: parse each top-level directory separately (ignoring top-level Go files)"`
+	Threads           int      `long:"threads" description:"The number of concurrent threads to use for parsing (units when splitting the project, otherwise packages)" default:"4"`
+	LoadAllPackages   bool     `long:"loadAllPackages" description:"Whether to type-check every package and dependency from source, instead of only loading packages with test files"`
+	BatchSize         int      `long:"batchSize" description:"The maximum number of packages to load into memory at once, or 0 to load every package at once (slower, but uses less memory for huge projects)"`
+	MemoryLimit       int      `long:"memoryLimit" description:"Soft limit for memory usage in MiB, which reduces the batch size when exceeded (uses --batchSize=32 if no batch size is specified)" value-name:"MiB"`
+	IncludeErrorFiles bool     `long:"includeErrorFiles" description:"Whether to analyze files with errors (e.g. unresolved imports) using incomplete type information instead of skipping them"`
+	IncludeGenerated  bool     `long:"includeGenerated" description:"Whether to parse generated files (with a \"Code generated ... DO NOT EDIT.\" comment) like regular files instead of skipping them"`
+	Include           []string `long:"include" description:"Only parse files matching this glob pattern, which can be a path relative to the project directory or a package import path (can be repeated)" value-name:"GLOB"`
+	Exclude           []string `long:"exclude" description:"Skip files matching this glob pattern, which can be a path relative to the project directory or a package import path (can be repeated)" value-name:"GLOB"`
+	TestPattern       string   `long:"run" description:"Only parse test cases whose names match this regular expression" value-name:"REGEXP"`
+	Tags              []string `long:"tags" description:"Comma-separated list of build tags to load packages with (can be repeated to load each set of tags separately)" value-name:"TAGS"`
+	Platforms         []string `long:"platform" description:"Target platform to load packages for, such as linux/arm64 (can be repeated to load each platform separately)" value-name:"GOOS/GOARCH"`
+	CacheDir          string   `long:"cacheDir" description:"Directory for caching per-package results between runs, so unchanged packages are not parsed again (disabled if empty)"`
+
+	LogLevel     string `long:"logLevel" short:"l" description:"The minimum severity of log message that should be displayed" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info"`
+	ProgressMode string `long:"progress" description:"How to report progress while parsing: a live status display, periodic log messages, or nothing (auto uses live on a terminal)" choice:"auto" choice:"live" choice:"log" choice:"none" default:"auto"`
+
+	// Canceled when the user interrupts the program, so the parser can stop early and report partial results.
+	// Set by the `main` function instead of a command-line flag.
+	Context context.Context `no-flag:"true"`
+
+	// Tracks and reports the progress of the parser, based on the `ProgressMode` flag.
+	// Set by the `main` function instead of a command-line flag.
+	Progress *progress.Tracker `no-flag:"true"`
+
+	// Selects which files and tests are parsed, based on the `Include`, `Exclude`, and `TestPattern` flags.
+	// Set by the `main` function instead of a command-line flag.
+	Filter *parser.Filter `no-flag:"true"`
 
-	LogLevel string `long:"logLevel" short:"l" description:"The minimum severity of log message that should be displayed" choice:"debug" choice:"info" choice:"warn" choice:"error" default:"info"`
+	// Every combination of build tags and target platforms that packages are loaded with, based on the `Tags` and
+	// `Platforms` flags. Set by the `main` function instead of a command-line flag.
+	BuildConfigs []parser.BuildConfig `no-flag:"true"`
 }
@@ -0,0 +1,200 @@
+package git
+
+// Determines which lines of a project's Go files have changed according to Git.
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Represents an inclusive range of 1-based line numbers in a file.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// Represents the set of lines that have changed in each Go file of a Git repository since some reference.
+// All file paths are absolute, with symbolic links resolved so they match paths given relative to a symlinked directory.
+type ChangeSet struct {
+	Ref   string                 // the Git reference that changes are relative to
+	files map[string][]LineRange // maps absolute file paths to the ranges of changed lines in the current version of the file
+
+	resolved sync.Map // caches the resolved version of each path looked up in the set, since lookups are frequent
+}
+
+// Find the lines of Go files that have changed in the Git repository containing `dir` since the specified reference
+// (e.g. a branch name, tag, or commit hash). Changes include committed, staged, and unstaged modifications,
+// as well as untracked files (which are treated as entirely changed). Deleted files are ignored.
+func ChangesSince(dir, ref string) (*ChangeSet, error) {
+	if ref == "" {
+		return nil, fmt.Errorf("empty Git reference provided")
+	}
+
+	root, err := RepoRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Git usually resolves symbolic links in the root itself, but make sure the stored paths never contain any
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	// Compare the reference with the working tree, only including the changed lines themselves (no context lines)
+	diff, err := runGit(root, "-c", "core.quotePath=false", "diff", "--unified=0", "--no-color", "--no-ext-diff", "--no-prefix", ref, "--", "*.go")
+	if err != nil {
+		return nil, fmt.Errorf("computing Git diff since %q: %w", ref, err)
+	}
+	files, err := parseDiff(strings.NewReader(diff), root)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Git diff since %q: %w", ref, err)
+	}
+
+	// Untracked files don't appear in the diff, so treat them as entirely new
+	untracked, err := runGit(root, "ls-files", "--others", "--exclude-standard", "-z", "--", "*.go")
+	if err != nil {
+		return nil, fmt.Errorf("listing untracked files: %w", err)
+	}
+	for _, file := range strings.Split(untracked, "\x00") {
+		if file != "" {
+			files[filepath.Join(root, filepath.FromSlash(file))] = []LineRange{{Start: 1, End: math.MaxInt}}
+		}
+	}
+
+	return &ChangeSet{Ref: ref, files: files}, nil
+}
+
+// Matches the header of a diff hunk, capturing the start line and line count of the new version of the file
+var hunkHeaderRegex = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse the output of `git diff --unified=0 --no-prefix`, returning the changed line ranges for each file.
+// File paths are resolved relative to the repository root.
+func parseDiff(r io.Reader, root string) (map[string][]LineRange, error) {
+	files := make(map[string][]LineRange)
+	var current string // the absolute path of the file whose hunks are being parsed, or empty if it was deleted
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // Allow long lines, which are common in generated files
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.HasPrefix(line, "+++ "):
+			path := strings.TrimPrefix(line, "+++ ")
+			if path == "/dev/null" {
+				current = "" // The file was deleted, so none of its lines exist anymore
+				continue
+			}
+			if unquoted, err := strconv.Unquote(path); err == nil {
+				path = unquoted // Paths with special characters are quoted by Git
+			}
+			current = filepath.Join(root, filepath.FromSlash(path))
+
+		case strings.HasPrefix(line, "@@ "):
+			if current == "" {
+				continue
+			}
+			match := hunkHeaderRegex.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("malformed hunk header %q", line)
+			}
+			start, _ := strconv.Atoi(match[1])
+			count := 1
+			if match[2] != "" {
+				count, _ = strconv.Atoi(match[2])
+			}
+
+			if count == 0 {
+				// Lines were only removed, so mark the lines on either side of the removal as changed
+				files[current] = append(files[current], LineRange{Start: max(start, 1), End: start + 1})
+			} else {
+				files[current] = append(files[current], LineRange{Start: start, End: start + count - 1})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Return the absolute version of the path with any symbolic links resolved, so it can be compared to the paths in the set.
+// Paths that can't be resolved (e.g. because they don't exist) are only cleaned.
+func (cs *ChangeSet) resolve(path string) string {
+	if resolved, ok := cs.resolved.Load(path); ok {
+		return resolved.(string)
+	}
+	resolved := filepath.Clean(path)
+	if abs, err := filepath.Abs(path); err == nil {
+		resolved = abs
+	}
+	if target, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = target
+	}
+	cs.resolved.Store(path, resolved)
+	return resolved
+}
+
+// Return whether the specified file has any changes.
+func (cs *ChangeSet) HasFile(path string) bool {
+	_, ok := cs.files[cs.resolve(path)]
+	return ok
+}
+
+// Return whether any of the specified files has changes.
+func (cs *ChangeSet) HasAnyFile(paths []string) bool {
+	return slices.ContainsFunc(paths, cs.HasFile)
+}
+
+// Return whether any changed file is inside the specified directory, including its subdirectories.
+func (cs *ChangeSet) HasFilesIn(dir string) bool {
+	dir = cs.resolve(dir)
+	for path := range cs.files {
+		if rel, err := filepath.Rel(dir, path); err == nil && filepath.IsLocal(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return whether any changed lines in the specified file are within the inclusive range from `start` to `end`.
+func (cs *ChangeSet) Overlaps(path string, start, end int) bool {
+	for _, r := range cs.files[cs.resolve(path)] {
+		if r.Start <= end && start <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the number of files with changes.
+func (cs *ChangeSet) NumFiles() int {
+	return len(cs.files)
+}
+
+// Return a hash representing every change in the set, which changes whenever the set of changed lines does.
+func (cs *ChangeSet) Digest() string {
+	paths := make([]string, 0, len(cs.files))
+	for path := range cs.files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		fmt.Fprintf(h, "%s\x00", path)
+		for _, r := range cs.files[path] {
+			fmt.Fprintf(h, "%d-%d\x00", r.Start, r.End)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,242 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	root := filepath.FromSlash("/repo")
+	abs := func(path string) string { return filepath.Join(root, filepath.FromSlash(path)) }
+
+	tests := []struct {
+		name string
+		diff string
+		want map[string][]LineRange
+	}{
+		{
+			name: "empty diff",
+			diff: "",
+			want: map[string][]LineRange{},
+		},
+		{
+			name: "modified lines",
+			diff: `diff --git a.go a.go
+index 1111111..2222222 100644
+--- a.go
++++ a.go
+@@ -3,2 +3,4 @@ func A() {
+`,
+			want: map[string][]LineRange{abs("a.go"): {{Start: 3, End: 6}}},
+		},
+		{
+			name: "omitted counts mean a single line",
+			diff: `--- pkg/b.go
++++ pkg/b.go
+@@ -10 +12 @@
+`,
+			want: map[string][]LineRange{abs("pkg/b.go"): {{Start: 12, End: 12}}},
+		},
+		{
+			name: "omitted old count",
+			diff: `--- b.go
++++ b.go
+@@ -7 +7,3 @@
+`,
+			want: map[string][]LineRange{abs("b.go"): {{Start: 7, End: 9}}},
+		},
+		{
+			name: "removed lines mark both neighbors",
+			diff: `--- c.go
++++ c.go
+@@ -5,2 +4,0 @@
+`,
+			want: map[string][]LineRange{abs("c.go"): {{Start: 4, End: 5}}},
+		},
+		{
+			name: "removed lines at the start of the file",
+			diff: `--- c.go
++++ c.go
+@@ -1,2 +0,0 @@
+`,
+			want: map[string][]LineRange{abs("c.go"): {{Start: 1, End: 1}}},
+		},
+		{
+			name: "several hunks and files",
+			diff: `--- a.go
++++ a.go
+@@ -1 +1 @@
+@@ -20,0 +21,2 @@
+--- dir/d.go
++++ dir/d.go
+@@ -3,3 +3,3 @@
+`,
+			want: map[string][]LineRange{
+				abs("a.go"):     {{Start: 1, End: 1}, {Start: 21, End: 22}},
+				abs("dir/d.go"): {{Start: 3, End: 5}},
+			},
+		},
+		{
+			name: "new file",
+			diff: `diff --git new.go new.go
+new file mode 100644
+--- /dev/null
++++ new.go
+@@ -0,0 +1,3 @@
+`,
+			want: map[string][]LineRange{abs("new.go"): {{Start: 1, End: 3}}},
+		},
+		{
+			name: "deleted file is ignored",
+			diff: `diff --git old.go old.go
+deleted file mode 100644
+--- old.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+--- kept.go
++++ kept.go
+@@ -2 +2 @@
+`,
+			want: map[string][]LineRange{abs("kept.go"): {{Start: 2, End: 2}}},
+		},
+		{
+			name: "renamed file with changes uses the new path",
+			diff: `diff --git old/e.go new/e.go
+similarity index 90%
+rename from old/e.go
+rename to new/e.go
+--- old/e.go
++++ new/e.go
+@@ -4 +4 @@
+`,
+			want: map[string][]LineRange{abs("new/e.go"): {{Start: 4, End: 4}}},
+		},
+		{
+			name: "renamed file without changes has no changed lines",
+			diff: `diff --git old/f.go new/f.go
+similarity index 100%
+rename from old/f.go
+rename to new/f.go
+`,
+			want: map[string][]LineRange{},
+		},
+		{
+			name: "quoted path",
+			diff: `--- "dir/a\tb.go"
++++ "dir/a\tb.go"
+@@ -1 +1 @@
+`,
+			want: map[string][]LineRange{abs("dir/a\tb.go"): {{Start: 1, End: 1}}},
+		},
+		{
+			name: "content lines resembling headers are ignored",
+			diff: `--- g.go
++++ g.go
+@@ -1,2 +1,2 @@
+-// @@ not a hunk
++// ++ still not a header
+`,
+			want: map[string][]LineRange{abs("g.go"): {{Start: 1, End: 2}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDiff(strings.NewReader(tt.diff), root)
+			if err != nil {
+				t.Fatalf("parseDiff returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDiff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDiffMalformedHunk(t *testing.T) {
+	diff := "--- a.go\n+++ a.go\n@@ -1 +x @@\n"
+	if _, err := parseDiff(strings.NewReader(diff), "/repo"); err == nil {
+		t.Error("parseDiff accepted a malformed hunk header")
+	}
+}
+
+func TestChangeSetOverlaps(t *testing.T) {
+	path := filepath.FromSlash("/repo/a.go")
+	cs := &ChangeSet{files: map[string][]LineRange{path: {{Start: 5, End: 8}, {Start: 20, End: 20}}}}
+	tests := []struct {
+		start, end int
+		want       bool
+	}{
+		{1, 4, false},
+		{1, 5, true},
+		{6, 7, true},
+		{8, 19, true},
+		{9, 19, false},
+		{20, 30, true},
+		{21, 30, false},
+	}
+	for _, tt := range tests {
+		if got := cs.Overlaps(path, tt.start, tt.end); got != tt.want {
+			t.Errorf("Overlaps(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestChangeSetSymlinks(t *testing.T) {
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := filepath.Join(root, "repo")
+	file := filepath.Join(repo, "pkg", "a.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(repo, link); err != nil {
+		t.Skipf("cannot create symbolic links: %v", err)
+	}
+
+	cs := &ChangeSet{files: map[string][]LineRange{file: {{Start: 1, End: 1}}}}
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"resolved path", file, true},
+		{"path through symlink", filepath.Join(link, "pkg", "a.go"), true},
+		{"unclean path through symlink", filepath.Join(link, "pkg", "..", "pkg", "a.go"), true},
+		{"missing file", filepath.Join(link, "pkg", "b.go"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cs.HasFile(tt.path); got != tt.want {
+				t.Errorf("HasFile(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+			if got := cs.Overlaps(tt.path, 1, 1); got != tt.want {
+				t.Errorf("Overlaps(%q, 1, 1) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	dirs := []struct {
+		dir  string
+		want bool
+	}{
+		{repo, true},
+		{link, true},
+		{filepath.Join(link, "pkg"), true},
+		{filepath.Join(link, "other"), false},
+		{filepath.Join(root, "rep"), false},
+	}
+	for _, tt := range dirs {
+		if got := cs.HasFilesIn(tt.dir); got != tt.want {
+			t.Errorf("HasFilesIn(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Utilities for working with Git repositories using the `git` command, such as finding changed lines and checking out past commits.
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Run a Git command in the specified directory, returning its standard output.
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// Return the absolute path of the root directory of the Git repository containing `dir`.
+func RepoRoot(dir string) (string, error) {
+	out, err := runGit(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("finding Git repository root for %q: %w", dir, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Represents a single commit in a repository's history.
+type Commit struct {
+	Hash string
+	Date time.Time // the committer date
+}
+
+// Return the commit hash shortened to a readable length.
+func (c Commit) ShortHash() string {
+	return c.Hash[:min(len(c.Hash), 12)]
+}
+
+// List the commits in the specified revision range (e.g. "v1.0..main", or "HEAD" for all ancestors of the current commit)
+// from oldest to newest, only following the first parent of merge commits so the history is linear.
+func FirstParentCommits(dir, revRange string) ([]Commit, error) {
+	out, err := runGit(dir, "log", "--first-parent", "--reverse", "--format=%H%x09%cI", revRange, "--")
+	if err != nil {
+		return nil, fmt.Errorf("listing commits in range %q: %w", revRange, err)
+	}
+
+	var commits []Commit
+	for line := range strings.Lines(out) {
+		hash, dateStr, found := strings.Cut(strings.TrimSpace(line), "\t")
+		if !found {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, dateStr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing date of commit %s: %w", hash, err)
+		}
+		commits = append(commits, Commit{Hash: hash, Date: date})
+	}
+	return commits, nil
+}
+
+// Check out the specified commit into a new detached worktree at `path`, which must not already exist.
+// The user's own checkout is never modified. The worktree should be removed using RemoveWorktree when it's no longer needed.
+func AddWorktree(repoDir, path, commit string) error {
+	if _, err := runGit(repoDir, "worktree", "add", "--detach", "--force", path, commit); err != nil {
+		return fmt.Errorf("creating worktree for commit %s: %w", commit, err)
+	}
+	return nil
+}
+
+// Remove a worktree created by AddWorktree, discarding any changes made inside it.
+func RemoveWorktree(repoDir, path string) error {
+	if _, err := runGit(repoDir, "worktree", "remove", "--force", path); err != nil {
+		return fmt.Errorf("removing worktree %q: %w", path, err)
+	}
+	return nil
+}
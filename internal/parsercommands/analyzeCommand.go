@@ -1,15 +1,20 @@
 package parsercommands
 
 import (
+	"cmp"
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/maxgreen01/go-test-parser/internal/config"
 	"github.com/maxgreen01/go-test-parser/internal/filewriter"
+	"github.com/maxgreen01/go-test-parser/internal/git"
 	"github.com/maxgreen01/go-test-parser/pkg/parser"
 	"github.com/maxgreen01/go-test-parser/pkg/testcase"
 	"golang.org/x/tools/go/packages"
@@ -24,16 +29,25 @@ type AnalyzeCommand struct {
 	globals *config.GlobalOptions // Avoid embedding this because the flag parser would treat it as duplicating the global options
 	analyzeOptions
 
-	// Output file writer
-	output *filewriter.FileWriter
+	// Output file writers. Near misses are written to a separate file, but only if the output is a CSV file.
+	output         *filewriter.FileWriter
+	nearMissOutput *filewriter.FileWriter
+
+	// Lines changed since the `since` Git reference, or nil if all tests should be analyzed.
+	// Shared by reference between clones.
+	changes *git.ChangeSet
 
 	// Data fields
-	testCases []*testcase.AnalysisResult // list of analysis results and related metadata for detected test functions
+	testCases  []analyzedTestCase  // condensed analysis results for detected test functions
+	nearMisses []testcase.NearMiss // functions that look like test functions, but aren't valid ones
 
 	tableDrivenTests            int // number of tests that are table-driven
+	scenarioCount               int // total number of scenarios defined across all table-driven tests
 	refactorAttempts            int // total number of test cases that were attempted to be refactored
 	refactorGenerationSuccesses int // number of test cases that were successfully refactored in some way
 	refactorSuccesses           int // number of test cases whose execution results matched before and after refactoring
+
+	partial bool // whether parsing was interrupted before every file was visited
 }
 
 // Command-line flags for the Analyze command specifically
@@ -41,10 +55,33 @@ type analyzeOptions struct {
 	// todo LATER/MAYBE make this a slice so multiple refactoring methods can be applied at once
 	RefactorStrategy    string `long:"refactor" description:"The type of refactoring to perform on the detected test cases" choice:"none" choice:"subtest" default:"none"`
 	KeepRefactoredFiles bool   `long:"keep-refactored-files" description:"Whether to retain the results of refactored test cases by NOT restoring the original source files after refactoring"`
+	Since               string `long:"since" description:"Only analyze tests whose code (including expanded helper functions) has changed since the specified Git reference" value-name:"REF"`
+}
+
+// Condensed representation of an analyzed test case, containing everything needed to report the results.
+// Unlike AnalysisResult, it doesn't reference any syntax data, so it's cheap to keep in memory and can be cached.
+type analyzedTestCase struct {
+	CSVRow []string `json:"csvRow"` // the test case's row in the CSV report
+
+	// The test case's package import path, file path, and name, which determine the order of the reported test cases
+	PkgPath  string `json:"pkgPath"`
+	FilePath string `json:"filePath"`
+	Name     string `json:"name"`
+
+	// The test case's full JSON analysis results, and the path where they're saved relative to the output directory.
+	// The JSON data is only kept when caching is enabled, so the file can be restored on later runs.
+	JSONPath string          `json:"jsonPath"`
+	JSON     json.RawMessage `json:"json,omitempty"`
 }
 
-// Compile-time interface implementation check
-var _ ParserCommand = (*AnalyzeCommand)(nil)
+// Compile-time interface implementation checks
+var (
+	_ preparableCommand    = (*AnalyzeCommand)(nil)
+	_ parser.CacheableTask = (*AnalyzeCommand)(nil)
+	_ parser.PartialTask   = (*AnalyzeCommand)(nil)
+	_ parser.SummaryTask   = (*AnalyzeCommand)(nil)
+	_ parser.ReleasingTask = (*AnalyzeCommand)(nil)
+)
 
 // Register the command with the global flag parser
 func init() {
@@ -70,7 +107,103 @@ func (cmd *AnalyzeCommand) Clone() parser.Task {
 		globals:        &globals,
 		analyzeOptions: cmd.analyzeOptions,
 		output:         cmd.output,
+		nearMissOutput: cmd.nearMissOutput,
+		changes:        cmd.changes,
+	}
+}
+
+// Combine the partial results collected by another AnalyzeCommand into this one.
+func (cmd *AnalyzeCommand) Merge(other parser.Task) error {
+	o, ok := other.(*AnalyzeCommand)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into %T", other, cmd)
+	}
+	for _, tc := range o.testCases {
+		tc.JSON = nil // Only needed for caching the results of individual packages, so avoid holding onto it
+		cmd.testCases = append(cmd.testCases, tc)
+	}
+	cmd.nearMisses = append(cmd.nearMisses, o.nearMisses...)
+	cmd.tableDrivenTests += o.tableDrivenTests
+	cmd.scenarioCount += o.scenarioCount
+	cmd.refactorAttempts += o.refactorAttempts
+	cmd.refactorGenerationSuccesses += o.refactorGenerationSuccesses
+	cmd.refactorSuccesses += o.refactorSuccesses
+	cmd.partial = cmd.partial || o.partial
+	return nil
+}
+
+// Clear the information cached while analyzing the visited packages, so their memory can be reclaimed.
+func (cmd *AnalyzeCommand) ReleasePackages() {
+	testcase.ReleaseCaches()
+}
+
+// Mark the collected results as incomplete because parsing was interrupted.
+func (cmd *AnalyzeCommand) MarkPartial() {
+	cmd.partial = true
+}
+
+// Serializable representation of the results collected by an AnalyzeCommand, used for caching.
+type analyzeResults struct {
+	TestCases  []analyzedTestCase  `json:"testCases"`
+	NearMisses []testcase.NearMiss `json:"nearMisses"`
+
+	TableDrivenTests            int `json:"tableDrivenTests"`
+	ScenarioCount               int `json:"scenarioCount"`
+	RefactorAttempts            int `json:"refactorAttempts"`
+	RefactorGenerationSuccesses int `json:"refactorGenerationSuccesses"`
+	RefactorSuccesses           int `json:"refactorSuccesses"`
+}
+
+// Return the options that affect the results of the analysis, i.e. the refactoring settings and the set of changed lines.
+func (cmd *AnalyzeCommand) CacheKey() string {
+	key := "refactor=" + cmd.RefactorStrategy + ",keep=" + strconv.FormatBool(cmd.KeepRefactoredFiles)
+	if cmd.changes != nil {
+		key += ",changes=" + cmd.changes.Digest()
 	}
+	return key
+}
+
+// Encode the test cases and counters collected by this instance of the command as JSON.
+func (cmd *AnalyzeCommand) EncodeResults() ([]byte, error) {
+	return json.Marshal(analyzeResults{
+		TestCases:                   cmd.testCases,
+		NearMisses:                  cmd.nearMisses,
+		TableDrivenTests:            cmd.tableDrivenTests,
+		ScenarioCount:               cmd.scenarioCount,
+		RefactorAttempts:            cmd.refactorAttempts,
+		RefactorGenerationSuccesses: cmd.refactorGenerationSuccesses,
+		RefactorSuccesses:           cmd.refactorSuccesses,
+	})
+}
+
+// Restore results that were previously encoded using `EncodeResults`, rewriting the JSON file of each test case
+// in case it was removed from the output directory since the results were cached.
+func (cmd *AnalyzeCommand) DecodeResults(data []byte) error {
+	var results analyzeResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return fmt.Errorf("decoding analysis results: %w", err)
+	}
+	for _, tc := range results.TestCases {
+		if len(tc.JSON) == 0 {
+			continue
+		}
+		path := filepath.Join(cmd.output.GetPathDir(), tc.JSONPath)
+		// Write the raw JSON data as a single element, rather than as a slice of bytes
+		if err := filewriter.WriteToFile(path, tc.JSON, false); err != nil {
+			slog.Error("Restoring cached test case JSON", "err", err, "path", path)
+		}
+	}
+
+	cmd.globals.Progress.Unit(cmd.globals.ProjectDir).TestsAnalyzed(len(results.TestCases))
+
+	cmd.testCases = results.TestCases
+	cmd.nearMisses = results.NearMisses
+	cmd.tableDrivenTests = results.TableDrivenTests
+	cmd.scenarioCount = results.ScenarioCount
+	cmd.refactorAttempts = results.RefactorAttempts
+	cmd.refactorGenerationSuccesses = results.RefactorGenerationSuccesses
+	cmd.refactorSuccesses = results.RefactorSuccesses
+	return nil
 }
 
 // Set the project directory for this task.
@@ -78,24 +211,54 @@ func (cmd *AnalyzeCommand) SetProjectDir(dir string) {
 	cmd.globals.ProjectDir = dir
 }
 
-// Validate the values of this Command's flags, then run the task itself
+// Validate the values of this Command's flags, then run the task itself.
 // THIS SHOULD ONLY BE CALLED ONCE PER PROGRAM EXECUTION.
 func (cmd *AnalyzeCommand) Execute(args []string) error {
+	if err := cmd.prepare(); err != nil {
+		return err
+	}
+
+	// Actually run the task by starting the parser
+	return parser.Parse(cmd.globals.Context, cmd, cmd.globals.ProjectDir, parserOptions(cmd.globals))
+}
+
+// Validate the values of this Command's flags and initialize the output writer, without starting the parser.
+func (cmd *AnalyzeCommand) prepare() error {
 	if cmd.globals.OutputPath == "" {
 		cmd.globals.OutputPath = "analyze_report.csv"
 	}
 	// Initialize the output writer with the specified output path
 	writer, err := filewriter.NewFileWriter(cmd.globals.OutputPath, cmd.globals.AppendOutput)
 	if err != nil {
-		return fmt.Errorf("creating output writer for path %q", cmd.globals.OutputPath)
+		return fmt.Errorf("creating output writer for path %q: %w", cmd.globals.OutputPath, err)
 	}
 	cmd.output = writer
+	if writer.DetectFormat() == filewriter.FormatCSV {
+		path := siblingPath(writer.GetPath(), "nearmisses")
+		cmd.nearMissOutput, err = filewriter.NewFileWriter(path, cmd.globals.AppendOutput)
+		if err != nil {
+			return fmt.Errorf("creating near-miss output writer for path %q: %w", path, err)
+		}
+	}
 
 	// Validate refactoring strategy. Allowed options are handled by the `choice` tag in the struct definition.
 	cmd.RefactorStrategy = strings.ToLower(strings.TrimSpace(cmd.RefactorStrategy))
 
-	// Actually run the task by starting the parser
-	return parser.Parse(cmd, cmd.globals.ProjectDir, cmd.globals.SplitByDir, cmd.globals.Threads)
+	// Find the changed lines once, so every directory and clone uses the same set of changes
+	cmd.Since = strings.TrimSpace(cmd.Since)
+	if cmd.Since != "" {
+		changes, err := git.ChangesSince(cmd.globals.ProjectDir, cmd.Since)
+		if err != nil {
+			return fmt.Errorf("finding changes since %q: %w", cmd.Since, err)
+		}
+		slog.Info("Only analyzing tests affected by changes", "since", cmd.Since, "changedFiles", changes.NumFiles())
+		if changes.NumFiles() > 0 && !changes.HasFilesIn(cmd.globals.ProjectDir) {
+			slog.Warn("None of the changed files are in the project directory, so no tests will be analyzed", "since", cmd.Since, "dir", cmd.globals.ProjectDir)
+		}
+		cmd.changes = changes
+	}
+
+	return nil
 }
 
 // Extract test cases from the given file, analyze them, and potentially refactor them before saving the results to JSON files.
@@ -104,6 +267,15 @@ func (cmd *AnalyzeCommand) Visit(file *ast.File, fset *token.FileSet, pkg *packa
 	// packageName := file.Name.Name
 	// filePath := fset.Position(file.FileStart).Filename
 
+	// Tests can only be affected by changes within their own package, so skip the file if the package is unchanged
+	if cmd.changes != nil && !cmd.changes.HasAnyFile(pkg.CompiledGoFiles) {
+		return
+	}
+
+	// Find the testify suites whose methods are defined in this file
+	suites := testcase.FindSuites(file, pkg)
+	testFile := testcase.IsTestFile(file, fset)
+
 	// Only iterate top level declarations
 	for _, decl := range file.Decls {
 		fn, ok := decl.(*ast.FuncDecl)
@@ -113,56 +285,260 @@ func (cmd *AnalyzeCommand) Visit(file *ast.File, fset *token.FileSet, pkg *packa
 
 		// slog.Debug("Checking function...", "name", fn.Name.Name, "package", packageName, "file", filePath)
 
-		// Save the function as a valid test case if it meets all the criteria
-		valid, _ := testcase.IsValidTestCase(fn)
-		// todo do something with the `badFormat` return value
-		if !valid {
+		if !cmd.globals.Filter.MatchTest(fn.Name.Name) {
 			continue
 		}
-		tc := testcase.CreateTestCase(fn, file, pkg, projectName)
 
-		// Analyze and store the test case
-		analysisResult := testcase.Analyze(&tc)
-		cmd.testCases = append(cmd.testCases, analysisResult)
+		var tc testcase.TestCase
+		if suite := testcase.SuiteOf(fn, suites); suite != nil {
+			// Save methods of testify suites as test cases, even though they aren't test functions themselves
+			tc = testcase.CreateSuiteTestCase(fn, file, pkg, projectName, suite)
+		} else {
+			// Record functions that look like test functions but won't be run, so they can be reported separately
+			if nm := testcase.FindNearMiss(fn, file, fset, projectName); nm != nil {
+				cmd.nearMisses = append(cmd.nearMisses, *nm)
+				continue
+			}
 
-		if analysisResult.IsTableDriven() {
-			cmd.tableDrivenTests++
+			// Save the function as a valid test case (of any kind) if it meets all the criteria and is in a test file
+			_, valid, _ := testcase.ClassifyFunction(fn)
+			// todo do something with the `badFormat` return value
+			if !valid || !testFile {
+				continue
+			}
+			tc = testcase.CreateTestCase(fn, file, pkg, projectName)
 		}
 
-		// Attempt to refactor the test case if a refactoring strategy is specified
-		result := analysisResult.AttemptRefactoring(testcase.RefactorStrategyFromString(cmd.RefactorStrategy), cmd.KeepRefactoredFiles)
+		cmd.analyzeTestCase(&tc, pkg)
+	}
 
-		// Only count refactoring statistics if a refactoring strategy was specified
-		if result.Strategy != testcase.RefactorStrategyNone && result.GenerationStatus != testcase.RefactorGenerationStatusNone {
-			// A refactoring attempt was made
-			cmd.refactorAttempts++
+	// Ginkgo specs are defined inside the function literals passed to Ginkgo's container functions, rather than as
+	// separate test functions, so analyze each spec as its own test case
+	for _, spec := range testcase.FindGinkgoSpecs(file, pkg) {
+		if !cmd.globals.Filter.MatchTest(spec.FullText()) {
+			continue
+		}
+		tc := testcase.CreateGinkgoTestCase(spec, file, pkg, projectName)
+		cmd.analyzeTestCase(&tc, pkg)
+	}
+}
 
-			if result.GenerationStatus == testcase.RefactorGenerationStatusSuccess {
-				// The refactoring generation succeeded
-				cmd.refactorGenerationSuccesses++
+// Analyze a single test case, potentially refactor it, and save the results.
+func (cmd *AnalyzeCommand) analyzeTestCase(tc *testcase.TestCase, pkg *packages.Package) {
+	// Analyze the test case
+	analysisResult := testcase.Analyze(tc)
+	cmd.globals.Progress.Unit(cmd.globals.ProjectDir).TestsAnalyzed(1)
 
-				if result.OriginalExecutionResult == result.RefactoredExecutionResult && result.OriginalExecutionResult == testcase.TestExecutionResultPass {
-					// The refactoring generation was successful, and the execution results are both successful too
-					cmd.refactorSuccesses++
-				}
+	if cmd.changes != nil && !affectedByChanges(analysisResult, cmd.changes) {
+		slog.Debug("Skipping test case unaffected by changes", "test", tc, "since", cmd.Since)
+		return
+	}
+
+	if analysisResult.IsTableDriven() {
+		cmd.tableDrivenTests++
+		cmd.scenarioCount += len(analysisResult.ScenarioSet.Scenarios)
+	}
+
+	// Attempt to refactor the test case if a refactoring strategy is specified.
+	// Refactoring is slow and modifies source files, so don't start new refactorings after being interrupted.
+	strategy := testcase.RefactorStrategyFromString(cmd.RefactorStrategy)
+	if strategy != testcase.RefactorStrategyNone && cmd.globals.Context.Err() != nil {
+		slog.Debug("Skipping refactoring because parsing was interrupted", "test", tc)
+		strategy = testcase.RefactorStrategyNone
+	}
+	result := analysisResult.AttemptRefactoring(cmd.globals.Context, strategy, cmd.KeepRefactoredFiles)
+
+	// Only count refactoring statistics if a refactoring strategy was specified
+	if result.Strategy != testcase.RefactorStrategyNone && result.GenerationStatus != testcase.RefactorGenerationStatusNone {
+		// A refactoring attempt was made
+		cmd.refactorAttempts++
+
+		if result.GenerationStatus == testcase.RefactorGenerationStatusSuccess {
+			// The refactoring generation succeeded
+			cmd.refactorGenerationSuccesses++
+			cmd.globals.Progress.Unit(cmd.globals.ProjectDir).RefactorExecuted()
+
+			if result.OriginalExecutionResult == result.RefactoredExecutionResult && result.OriginalExecutionResult == testcase.TestExecutionResultPass {
+				// The refactoring generation was successful, and the execution results are both successful too
+				cmd.refactorSuccesses++
 			}
 		}
+	}
 
-		// Write all results to a JSON file
-		err := analysisResult.SaveAsJSON(cmd.output.GetPathDir())
+	// Write all results to a JSON file
+	err := analysisResult.SaveAsJSON(cmd.output.GetPathDir())
+	if err != nil {
+		slog.Error("Saving test case as JSON", "err", err, "test", tc)
+	}
+
+	// Store a condensed version of the results, only keeping the full JSON if it may need to be cached
+	analyzed := analyzedTestCase{
+		CSVRow:   analysisResult.EncodeAsCSV(),
+		PkgPath:  pkg.PkgPath,
+		FilePath: tc.FilePath,
+		Name:     tc.TestName,
+		JSONPath: tc.GetJSONFilePath(""),
+	}
+	if cmd.globals.CacheDir != "" {
+		data, err := json.Marshal(analysisResult)
 		if err != nil {
-			slog.Error("Saving test case as JSON", "err", err, "test", tc)
+			slog.Error("Encoding test case as JSON for caching", "err", err, "test", tc)
+		} else {
+			analyzed.JSON = data
 		}
 	}
+	cmd.testCases = append(cmd.testCases, analyzed)
+}
+
+// Return whether any changed lines are inside the test function or any of the statements it expands to,
+// which includes the bodies of the helper functions it calls.
+func affectedByChanges(ar *testcase.AnalysisResult, changes *git.ChangeSet) bool {
+	tc := ar.TestCase
+	fset := tc.FileSet()
+	overlaps := func(node ast.Node) bool {
+		if node == nil || !node.Pos().IsValid() {
+			return false
+		}
+		start, end := fset.Position(node.Pos()), fset.Position(node.End())
+		return changes.Overlaps(start.Filename, start.Line, end.Line)
+	}
+
+	if overlaps(tc.GetFuncDecl()) {
+		return true
+	}
+	for _, stmt := range ar.ParsedStatements {
+		for inner := range stmt.All() {
+			if overlaps(inner) {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // Summarize the results of the entire analysis in one file, leaving the bulk of the specific data about each
 // test case in its corresponding JSON file that was saved previously.
 func (cmd *AnalyzeCommand) ReportResults() error {
-	// Format output for printing the report to the terminal (and potentially writing to a text file)
+	reportLines := cmd.formatResults(fmt.Sprintf("Analysis Report for %q", cmd.globals.ProjectDir))
+
+	// Print the report to the terminal
+	slog.Info("Finished running analysis task on project \"" + cmd.globals.ProjectDir + "\"")
+	fmt.Print(strings.Join(reportLines, "") + "\n")
+
+	// Save every near miss in a single JSON file, like the results of each test case
+	slices.SortStableFunc(cmd.nearMisses, func(a, b testcase.NearMiss) int {
+		return cmp.Or(strings.Compare(a.FilePath, b.FilePath), cmp.Compare(a.Line, b.Line))
+	})
+	if len(cmd.nearMisses) > 0 {
+		projectName := filepath.Base(cmd.globals.ProjectDir)
+		path := filepath.Join(cmd.output.GetPathDir(), projectName, projectName+"_nearmisses.json")
+		if err := filewriter.WriteToFile(path, cmd.nearMisses, false); err != nil {
+			slog.Error("Saving near misses as JSON", "err", err, "path", path)
+		}
+	}
+
+	// Append results to output file (text or CSV)
+	switch cmd.output.DetectFormat() {
+
+	case filewriter.FormatTxt:
+		for _, nm := range cmd.nearMisses {
+			reportLines = append(reportLines, fmt.Sprintf("  - %s (%s:%d): %s [%s]\n", nm.Name, nm.FilePath, nm.Line, nm.Reason.Description(), nm.Reason))
+		}
+		return cmd.output.Write(reportLines)
+
+	case filewriter.FormatCSV:
+		if len(cmd.nearMisses) > 0 {
+			rows := make([][]string, 0, len(cmd.nearMisses))
+			for _, nm := range cmd.nearMisses {
+				rows = append(rows, append(nm.EncodeAsCSV(), strconv.FormatBool(cmd.partial)))
+			}
+			headers := append(new(testcase.NearMiss).GetCSVHeaders(), "partial")
+			if err := cmd.nearMissOutput.WriteMultiple(rows, headers); err != nil {
+				return err
+			}
+		}
+
+		numTests := len(cmd.testCases)
+		if numTests == 0 {
+			return nil
+		}
+
+		// Save a condensed version of each analyzed test case, noting whether it's part of an incomplete report.
+		// Test cases are collected in whatever order their packages are visited, so sort them to keep the output consistent.
+		slices.SortStableFunc(cmd.testCases, func(a, b analyzedTestCase) int {
+			return cmp.Or(
+				strings.Compare(a.PkgPath, b.PkgPath),
+				strings.Compare(a.FilePath, b.FilePath),
+				strings.Compare(a.Name, b.Name),
+			)
+		})
+		rows := make([][]string, 0, numTests)
+		for _, tc := range cmd.testCases {
+			rows = append(rows, append(slices.Clone(tc.CSVRow), strconv.FormatBool(cmd.partial)))
+		}
+		headers := append(new(testcase.AnalysisResult).GetCSVHeaders(), "partial")
+		return cmd.output.WriteMultiple(rows, headers)
+
+	default:
+		return fmt.Errorf("unsupported output format (file %q)", cmd.output.GetPath())
+	}
+}
+
+// Write the combined results of every unit after the project was split. Unlike the per-unit CSV reports, which contain
+// a row for each test case, the CSV summary contains a single row with the combined totals and ratios.
+func (cmd *AnalyzeCommand) ReportSummary(units int) error {
+	reportLines := cmd.formatResults(fmt.Sprintf("Analysis Summary of %d Units in %q", units, cmd.globals.ProjectDir))
+
+	// Print the summary to the terminal
+	slog.Info("Finished running analysis task on every unit of project \"" + cmd.globals.ProjectDir + "\"")
+	fmt.Print(strings.Join(reportLines, "") + "\n")
+
+	csvHeaders := []string{
+		"projectDir",
+		"units",
+		"testCases",
+		"tableDrivenTests",
+		"percentTableDriven",
+		"scenarios",
+		"avgScenariosPerTableDrivenTest",
+		"refactorAttempts",
+		"refactorGenerationSuccesses",
+		"refactorSuccesses",
+		"nearMisses",
+		"partial",
+	}
+
+	percentTableDriven, avgScenarios := cmd.tableDrivenRatios()
+	row := []string{
+		cmd.globals.ProjectDir,
+		strconv.Itoa(units),
+		strconv.Itoa(len(cmd.testCases)),
+		strconv.Itoa(cmd.tableDrivenTests),
+		fmt.Sprintf("%.1f", percentTableDriven),
+		strconv.Itoa(cmd.scenarioCount),
+		fmt.Sprintf("%.1f", avgScenarios),
+		strconv.Itoa(cmd.refactorAttempts),
+		strconv.Itoa(cmd.refactorGenerationSuccesses),
+		strconv.Itoa(cmd.refactorSuccesses),
+		strconv.Itoa(len(cmd.nearMisses)),
+		strconv.FormatBool(cmd.partial),
+	}
+
+	return writeSummary(cmd.output, cmd.globals.AppendOutput, reportLines, csvHeaders, row)
+}
 
+// Format the collected results as the lines of a text report with the specified title.
+func (cmd *AnalyzeCommand) formatResults(title string) []string {
 	reportLines := []string{
-		fmt.Sprintf("\n=============  Analysis Report for %q:  =============\n\n", cmd.globals.ProjectDir),
+		fmt.Sprintf("\n=============  %s:  =============\n\n", title),
+	}
+
+	if cmd.partial {
+		reportLines = append(reportLines, "WARNING: Parsing was interrupted, so these results are incomplete!\n\n")
+	}
+
+	if cmd.changes != nil {
+		reportLines = append(reportLines, fmt.Sprintf("Only analyzing tests affected by changes since %q\n\n", cmd.Since))
 	}
 
 	numTests := len(cmd.testCases)
@@ -170,10 +546,13 @@ func (cmd *AnalyzeCommand) ReportResults() error {
 	if numTests == 0 {
 		reportLines = append(reportLines, "No test cases found in the specified project.\n\n")
 	} else {
+		percentTableDriven, avgScenarios := cmd.tableDrivenRatios()
 		reportLines = append(reportLines,
 			fmt.Sprintf("Number of test cases: %d\n", numTests),
 			"\n",
-			fmt.Sprintf("Table-driven tests: %d\n", cmd.tableDrivenTests),
+			fmt.Sprintf("Table-driven tests: %d (%.1f%% of test cases)\n", cmd.tableDrivenTests, percentTableDriven),
+			fmt.Sprintf("Scenarios in table-driven tests: %d\n", cmd.scenarioCount),
+			fmt.Sprintf("Average scenarios per table-driven test: %.1f\n", avgScenarios),
 			"\n",
 			fmt.Sprintf("Refactoring strategy: %q\n", cmd.RefactorStrategy),
 		)
@@ -187,36 +566,31 @@ func (cmd *AnalyzeCommand) ReportResults() error {
 		}
 	}
 
-	// Print the report to the terminal
-	slog.Info("Finished running analysis task on project \"" + cmd.globals.ProjectDir + "\"")
-	fmt.Print(strings.Join(reportLines, "") + "\n")
-
-	// Append results to output file (text or CSV)
-	switch cmd.output.DetectFormat() {
-
-	case filewriter.FormatTxt:
-		return cmd.output.Write(reportLines)
-
-	case filewriter.FormatCSV:
-		if numTests == 0 {
-			return nil
-		}
+	if len(cmd.nearMisses) > 0 {
+		reportLines = append(reportLines, fmt.Sprintf("\nNear-miss test functions (which look like tests, but won't be run): %d\n", len(cmd.nearMisses)))
+	}
 
-		// Save a condensed version of each analyzed test case
-		rows := make([][]string, 0, numTests)
-		for _, tc := range cmd.testCases {
-			rows = append(rows, tc.EncodeAsCSV())
-		}
-		return cmd.output.WriteMultiple(rows, cmd.testCases[0].GetCSVHeaders())
+	return reportLines
+}
 
-	default:
-		return fmt.Errorf("unsupported output format (file %q)", cmd.output.GetPath())
+// Return the percentage of test cases that are table-driven, and the average number of scenarios per table-driven test.
+// Both are calculated from the totals, so they're also correct for the combined results of several units.
+func (cmd *AnalyzeCommand) tableDrivenRatios() (percentTableDriven, avgScenarios float64) {
+	if numTests := len(cmd.testCases); numTests > 0 {
+		percentTableDriven = float64(cmd.tableDrivenTests) / float64(numTests) * 100
 	}
+	if cmd.tableDrivenTests > 0 {
+		avgScenarios = float64(cmd.scenarioCount) / float64(cmd.tableDrivenTests)
+	}
+	return percentTableDriven, avgScenarios
 }
 
-// Close the output file writer
+// Close the output file writers
 func (cmd *AnalyzeCommand) Close() {
 	if cmd.output != nil {
 		cmd.output.Close()
 	}
+	if cmd.nearMissOutput != nil {
+		cmd.nearMissOutput.Close()
+	}
 }
@@ -0,0 +1,298 @@
+package parsercommands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/maxgreen01/go-test-parser/internal/config"
+	"github.com/maxgreen01/go-test-parser/internal/filewriter"
+	"github.com/maxgreen01/go-test-parser/internal/git"
+	"github.com/maxgreen01/go-test-parser/pkg/parser"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Implementation of the Flags package's Commander interface which runs the `statistics` and `analyze` tasks
+// on a sample of commits from a project's Git history, producing a time series of the results.
+// Each commit is checked out into a temporary worktree, so the user's own checkout is never modified.
+type HistoryCommand struct {
+	// Input flags
+	globals *config.GlobalOptions // Avoid embedding this because the flag parser would treat it as duplicating the global options
+	historyOptions
+
+	// Output file writer
+	output *filewriter.FileWriter
+}
+
+// Command-line flags for the History command specifically
+type historyOptions struct {
+	Range   string `long:"range" description:"The range of commits to sample, using Git revision range syntax (only following the first parent of merges)" default:"HEAD"`
+	Samples int    `long:"samples" description:"The maximum number of commits to analyze, spread evenly across the range (0 analyzes every commit)" default:"10"`
+}
+
+// Compile-time interface implementation check
+var _ Command = (*HistoryCommand)(nil)
+
+// Register the command with the global flag parser
+func init() {
+	RegisterCommand(func(flagParser *flags.Parser, opts *config.GlobalOptions) {
+		flagParser.AddCommand("history", "Track a Go project's test statistics across its Git history",
+			"Run the statistics and analyze tasks on a sample of commits from the project's Git history, producing one row of results per commit. "+
+				"The detailed reports for each commit are saved in a directory next to the `output` file.",
+			NewHistoryCommand(opts))
+	})
+}
+
+// Create a new instance of the HistoryCommand using a reference to the global options.
+func NewHistoryCommand(globals *config.GlobalOptions) *HistoryCommand {
+	return &HistoryCommand{globals: globals}
+}
+
+func (cmd *HistoryCommand) Name() string {
+	return "history"
+}
+
+// Represents the results of analyzing the project at a single commit
+type historyPoint struct {
+	commit git.Commit
+
+	testCases        int
+	tableDrivenTests int
+	scenarios        int
+	testLines        int
+	totalLines       int
+}
+
+// Return the headers for the CSV representation of a historyPoint
+func (p historyPoint) csvHeaders() []string {
+	return []string{
+		"commit",
+		"date",
+		"testCases",
+		"tableDrivenTests",
+		"tableDrivenRatio",
+		"scenarios",
+		"testLines",
+		"totalLines",
+		"percentTestLines",
+	}
+}
+
+// Encode the historyPoint as a CSV row corresponding to the headers in `csvHeaders()`
+func (p historyPoint) csvRow() []string {
+	return []string{
+		p.commit.Hash,
+		p.commit.Date.Format(time.RFC3339),
+		fmt.Sprintf("%d", p.testCases),
+		fmt.Sprintf("%d", p.tableDrivenTests),
+		fmt.Sprintf("%.3f", p.tableDrivenRatio()),
+		fmt.Sprintf("%d", p.scenarios),
+		fmt.Sprintf("%d", p.testLines),
+		fmt.Sprintf("%d", p.totalLines),
+		fmt.Sprintf("%.1f", p.percentTestLines()),
+	}
+}
+
+// Return a single line summarizing the historyPoint for text output
+func (p historyPoint) String() string {
+	return fmt.Sprintf("%s  %s  tests: %d, table-driven: %d (%.1f%%), scenarios: %d, test lines: %.1f%%\n",
+		p.commit.ShortHash(), p.commit.Date.Format(time.DateOnly), p.testCases, p.tableDrivenTests,
+		p.tableDrivenRatio()*100, p.scenarios, p.percentTestLines())
+}
+
+// Return the fraction of test cases that are table-driven
+func (p historyPoint) tableDrivenRatio() float64 {
+	if p.testCases == 0 {
+		return 0
+	}
+	return float64(p.tableDrivenTests) / float64(p.testCases)
+}
+
+// Return the percentage of the project's lines that are part of test cases
+func (p historyPoint) percentTestLines() float64 {
+	if p.totalLines == 0 {
+		return 0
+	}
+	return float64(p.testLines) / float64(p.totalLines) * 100
+}
+
+// Validate the values of this Command's flags, then analyze each sampled commit in order, writing one row of
+// results to the output file after each commit so partial results are kept if a later commit fails.
+// THIS SHOULD ONLY BE CALLED ONCE PER PROGRAM EXECUTION.
+func (cmd *HistoryCommand) Execute(args []string) error {
+	if cmd.Samples < 0 {
+		return fmt.Errorf("invalid number of samples %d, must be at least 0", cmd.Samples)
+	}
+	if parser.SplitModeFromString(cmd.globals.SplitBy) != parser.SplitNone {
+		slog.Warn("The splitBy option is not supported by the history command, so each commit is parsed as a whole")
+	}
+
+	if cmd.globals.OutputPath == "" {
+		cmd.globals.OutputPath = "history_report.csv"
+	}
+	writer, err := filewriter.NewFileWriter(cmd.globals.OutputPath, cmd.globals.AppendOutput)
+	if err != nil {
+		return fmt.Errorf("creating output writer for path %q: %w", cmd.globals.OutputPath, err)
+	}
+	cmd.output = writer
+	defer cmd.output.Close()
+
+	// Locate the project within its repository, so the same subdirectory can be found in each worktree
+	repoRoot, err := git.RepoRoot(cmd.globals.ProjectDir)
+	if err != nil {
+		return err
+	}
+	projectDir, err := filepath.EvalSymlinks(cmd.globals.ProjectDir)
+	if err != nil {
+		return fmt.Errorf("resolving project directory: %w", err)
+	}
+	relProjectDir, err := filepath.Rel(repoRoot, projectDir)
+	if err != nil {
+		return fmt.Errorf("finding project directory within repository %q: %w", repoRoot, err)
+	}
+
+	commits, err := git.FirstParentCommits(repoRoot, cmd.Range)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits found in range %q", cmd.Range)
+	}
+	sampled := sampleCommits(commits, cmd.Samples)
+	slog.Info("Analyzing project history", "range", cmd.Range, "commits", len(commits), "sampledCommits", len(sampled))
+
+	// Worktrees are placed in a consistent location for each repository so that cached results can be reused between runs
+	repoHash := sha256.Sum256([]byte(repoRoot))
+	worktreeRoot := filepath.Join(os.TempDir(), "testparser-history", hex.EncodeToString(repoHash[:])[:12])
+	reportsDir := strings.TrimSuffix(cmd.output.GetPath(), filepath.Ext(cmd.output.GetPath())) + "_commits"
+
+	var failures int
+	for i, commit := range sampled {
+		slog.Info(fmt.Sprintf("===== Analyzing commit %d of %d =====", i+1, len(sampled)), "commit", commit.ShortHash(), "date", commit.Date.Format(time.DateOnly))
+
+		point, err := cmd.analyzeCommit(commit, repoRoot, relProjectDir, worktreeRoot, filepath.Join(reportsDir, commit.ShortHash()))
+		if err != nil && cmd.globals.Context.Err() != nil {
+			// Interrupted, so don't start analyzing any more commits. The rows for previous commits are already saved.
+			return fmt.Errorf("analyzing commit %s: %w", commit.ShortHash(), err)
+		}
+		if err != nil {
+			slog.Error("Error analyzing commit", "err", err, "commit", commit.Hash)
+			failures++
+			continue
+		}
+
+		switch cmd.output.DetectFormat() {
+		case filewriter.FormatTxt:
+			err = cmd.output.Write(point.String())
+		case filewriter.FormatCSV:
+			err = cmd.output.Write(point.csvRow(), point.csvHeaders())
+		default:
+			return fmt.Errorf("unsupported output format (file %q)", cmd.output.GetPath())
+		}
+		if err != nil {
+			return fmt.Errorf("writing results for commit %s: %w", commit.Hash, err)
+		}
+	}
+
+	slog.Info("Finished analyzing project history", "analyzedCommits", len(sampled)-failures, "failedCommits", failures, "output", cmd.output.GetPath())
+	if failures == len(sampled) {
+		return fmt.Errorf("failed to analyze all %d sampled commits", failures)
+	}
+	return nil
+}
+
+// Check out the specified commit into a temporary worktree, then run the statistics and analyze tasks on the
+// project inside it, saving their reports to `reportsDir`. The worktree is always removed afterwards.
+func (cmd *HistoryCommand) analyzeCommit(commit git.Commit, repoRoot, relProjectDir, worktreeRoot, reportsDir string) (historyPoint, error) {
+	// Name the worktree after the repository so test case names in the reports match those of the original project.
+	// Commits are analyzed one at a time, so the same path is reused for every commit, which allows cached results
+	// (whose keys include file paths) to be reused for packages that didn't change between commits.
+	worktree := filepath.Join(worktreeRoot, filepath.Base(repoRoot))
+
+	// Clean up any worktree left behind by an interrupted run
+	if _, err := os.Stat(worktree); err == nil {
+		_ = git.RemoveWorktree(repoRoot, worktree)
+		os.RemoveAll(worktree)
+	}
+
+	if err := git.AddWorktree(repoRoot, worktree, commit.Hash); err != nil {
+		return historyPoint{}, err
+	}
+	defer func() {
+		if err := git.RemoveWorktree(repoRoot, worktree); err != nil {
+			slog.Warn("Could not remove temporary worktree", "err", err, "worktree", worktree)
+		}
+		os.RemoveAll(worktree)
+	}()
+
+	projectDir := filepath.Join(worktree, relProjectDir)
+	if info, err := os.Stat(projectDir); err != nil || !info.IsDir() {
+		return historyPoint{}, fmt.Errorf("project directory %q does not exist at this commit", relProjectDir)
+	}
+
+	// Create the tasks, each with its own copy of the global options
+	globals := *cmd.globals
+	globals.ProjectDir = projectDir
+	globals.SplitBy = "none"
+	globals.SplitByDir = false
+	globals.AppendOutput = false
+
+	statsGlobals := globals
+	statsGlobals.OutputPath = filepath.Join(reportsDir, "statistics_report.csv")
+	stats := NewStatisticsCommand(&statsGlobals)
+
+	analyzeGlobals := globals
+	analyzeGlobals.OutputPath = filepath.Join(reportsDir, "analyze_report.csv")
+	analyze := NewAnalyzeCommand(&analyzeGlobals)
+	analyze.RefactorStrategy = "none"
+
+	if err := stats.prepare(); err != nil {
+		return historyPoint{}, fmt.Errorf("preparing statistics task: %w", err)
+	}
+	if err := analyze.prepare(); err != nil {
+		stats.Close()
+		return historyPoint{}, fmt.Errorf("preparing analyze task: %w", err)
+	}
+
+	// Run both tasks in a single pass, which also closes their outputs unless parsing fails
+	if err := parser.ParseAll(globals.Context, []parser.Task{stats, analyze}, projectDir, parserOptions(&globals)); err != nil {
+		stats.Close()
+		analyze.Close()
+		return historyPoint{}, err
+	}
+
+	// The ratio of table-driven tests must be based on the test cases counted by the same task
+	return historyPoint{
+		commit:           commit,
+		testCases:        len(analyze.testCases),
+		tableDrivenTests: analyze.tableDrivenTests,
+		scenarios:        analyze.scenarioCount,
+		testLines:        stats.totalTestLines,
+		totalLines:       stats.totalLines,
+	}, nil
+}
+
+// Select at most `samples` commits spread evenly across the list, always including the first and last commits.
+// If `samples` is 0 or at least the number of commits, every commit is returned.
+func sampleCommits(commits []git.Commit, samples int) []git.Commit {
+	if samples == 0 || samples >= len(commits) {
+		return commits
+	}
+	if samples == 1 {
+		return commits[len(commits)-1:] // Only the latest commit
+	}
+
+	sampled := make([]git.Commit, samples)
+	for i := range samples {
+		// Round to the nearest index so the samples are as evenly spaced as possible
+		idx := (i*(len(commits)-1) + (samples-1)/2) / (samples - 1)
+		sampled[i] = commits[idx]
+	}
+	return sampled
+}
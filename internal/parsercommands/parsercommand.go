@@ -1,12 +1,25 @@
 package parsercommands
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
 	"github.com/maxgreen01/go-test-parser/internal/config"
+	"github.com/maxgreen01/go-test-parser/internal/filewriter"
 	"github.com/maxgreen01/go-test-parser/pkg/parser"
 
 	"github.com/jessevdk/go-flags"
 )
 
+// Represents any application command that can be executed from the command line.
+type Command interface {
+	flags.Commander
+
+	// Return the lowercase name of the command
+	Name() string
+}
+
 // Represents an application command that can be executed as a task by the parser.
 // This is a combination of both the Parser's Task interface and the Flags package's Commander interface.
 // Stores input flags for the task, as well as fields representing the data to be collected.
@@ -19,6 +32,62 @@ type ParserCommand interface {
 	flags.Commander
 }
 
+// Represents a ParserCommand that can be prepared to run without starting the parser itself,
+// so that it can be run alongside other tasks (e.g. by the `run` command).
+type preparableCommand interface {
+	ParserCommand
+
+	// Validate the values of the command's flags and initialize its output, without running the parser
+	prepare() error
+}
+
+// Build the parser options corresponding to the provided global options.
+func parserOptions(globals *config.GlobalOptions) parser.Options {
+	return parser.Options{
+		SplitBy:           parser.SplitModeFromString(globals.SplitBy),
+		Threads:           globals.Threads,
+		LoadAllPackages:   globals.LoadAllPackages,
+		BatchSize:         globals.BatchSize,
+		MemoryLimit:       int64(globals.MemoryLimit) << 20,
+		IncludeErrorFiles: globals.IncludeErrorFiles,
+		IncludeGenerated:  globals.IncludeGenerated,
+		BuildConfigs:      globals.BuildConfigs,
+		CacheDir:          globals.CacheDir,
+		Progress:          globals.Progress,
+		Filter:            globals.Filter,
+	}
+}
+
+// Write the summary of every unit's results, which is reported after the per-unit results when the project is split.
+// Text reports are appended to the output file itself, but CSV rows are written to a separate file next to it
+// (e.g. `statistics_report_summary.csv`), so the summary isn't counted twice when adding the per-unit rows together.
+func writeSummary(output *filewriter.FileWriter, appendOutput bool, reportLines, csvHeaders, row []string) error {
+	switch output.DetectFormat() {
+
+	case filewriter.FormatTxt:
+		return output.Write(reportLines)
+
+	case filewriter.FormatCSV:
+		path := siblingPath(output.GetPath(), "summary")
+		writer, err := filewriter.NewFileWriter(path, appendOutput)
+		if err != nil {
+			return fmt.Errorf("creating summary writer for path %q: %w", path, err)
+		}
+		defer writer.Close()
+		return writer.Write(row, csvHeaders)
+
+	default:
+		return fmt.Errorf("unsupported output format (file %q)", output.GetPath())
+	}
+}
+
+// Return the path of a file containing additional results next to the specified output file, by inserting the suffix
+// before the extension, like `report_summary.csv` for `report.csv`.
+func siblingPath(path, suffix string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + suffix + ext
+}
+
 // Stores anonymous functions used to register each command with the flag parser, which are all called by the `main` function.
 // Should only be modified by calling `RegisterCommand` in the `init` function of each command implementation.
 var CommandRegistry []func(*flags.Parser, *config.GlobalOptions)
@@ -0,0 +1,124 @@
+package parsercommands
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/maxgreen01/go-test-parser/internal/config"
+	"github.com/maxgreen01/go-test-parser/pkg/parser"
+
+	"github.com/jessevdk/go-flags"
+)
+
+// Implementation of the Flags package's Commander interface which runs multiple tasks in a single parsing pass,
+// so the packages in the project only have to be loaded and type-checked once.
+// Each task reports its results to its own output file.
+type RunCommand struct {
+	// Input flags
+	globals *config.GlobalOptions // Avoid embedding this because the flag parser would treat it as duplicating the global options
+	analyzeOptions
+
+	// The names of the tasks to run
+	Args struct {
+		Tasks []string `positional-arg-name:"tasks" description:"Comma-separated list of tasks to run, e.g. \"statistics,analyze\""`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Compile-time interface implementation check
+var _ Command = (*RunCommand)(nil)
+
+// Register the command with the global flag parser
+func init() {
+	RegisterCommand(func(flagParser *flags.Parser, opts *config.GlobalOptions) {
+		flagParser.AddCommand("run", "Run multiple tasks in a single parsing pass",
+			"Run multiple tasks (e.g. \"statistics,analyze\") while only loading the project's packages once. "+
+				"Each task writes its report to its own output file, named by inserting the task name before the extension of the `output` path.",
+			NewRunCommand(opts))
+	})
+}
+
+// Create a new instance of the RunCommand using a reference to the global options.
+func NewRunCommand(globals *config.GlobalOptions) *RunCommand {
+	return &RunCommand{globals: globals}
+}
+
+func (cmd *RunCommand) Name() string {
+	return "run"
+}
+
+// Create and prepare each of the requested tasks, then run all of them at once using the parser.
+// THIS SHOULD ONLY BE CALLED ONCE PER PROGRAM EXECUTION.
+func (cmd *RunCommand) Execute(args []string) error {
+	names := parseTaskNames(cmd.Args.Tasks)
+	if len(names) == 0 {
+		return fmt.Errorf("no tasks specified, expected a list like \"statistics,analyze\"")
+	}
+
+	tasks := make([]parser.Task, 0, len(names))
+	closeTasks := func() {
+		for _, t := range tasks {
+			t.Close()
+		}
+	}
+	for _, name := range names {
+		task, err := cmd.newTask(name)
+		if err != nil {
+			closeTasks()
+			return err
+		}
+		if err := task.prepare(); err != nil {
+			closeTasks()
+			return fmt.Errorf("preparing %s task: %w", name, err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	// Actually run the tasks by starting the parser
+	return parser.ParseAll(cmd.globals.Context, tasks, cmd.globals.ProjectDir, parserOptions(cmd.globals))
+}
+
+// Create a new command corresponding to the specified task name, using a copy of the global options
+// whose output path is specific to the task.
+func (cmd *RunCommand) newTask(name string) (preparableCommand, error) {
+	globals := *cmd.globals
+	globals.OutputPath = taskOutputPath(cmd.globals.OutputPath, name)
+
+	switch name {
+	case "statistics":
+		return NewStatisticsCommand(&globals), nil
+	case "analyze":
+		task := NewAnalyzeCommand(&globals)
+		task.analyzeOptions = cmd.analyzeOptions
+		return task, nil
+	default:
+		return nil, fmt.Errorf("unknown task %q, expected \"statistics\" or \"analyze\"", name)
+	}
+}
+
+// Split the provided arguments into individual lowercase task names, separated by commas or whitespace.
+// Duplicate task names are only included once.
+func parseTaskNames(args []string) []string {
+	var names []string
+	for _, arg := range args {
+		for _, name := range strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == ' ' }) {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// Return the output path for a specific task by inserting the task name before the file extension,
+// e.g. "report.csv" becomes "report_analyze.csv". Returns an empty string if the path is empty, so the
+// task uses its own default output path instead.
+func taskOutputPath(path, task string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + task + ext
+}
@@ -1,11 +1,14 @@
 package parsercommands
 
 import (
+	"encoding/json"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"log/slog"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/maxgreen01/go-test-parser/internal/config"
@@ -28,20 +31,35 @@ type StatisticsCommand struct {
 	output *filewriter.FileWriter
 
 	// Data fields
-	testCases []testcase.TestCase // list of actual test functions and related metadata
-
-	testFileCount  int // total number of files ending in "_test.go"
-	totalFileCount int // total number of Go files
-	totalTestLines int // total number of lines in all test functions
-	totalLines     int // total number of lines across the entire project
+	testCaseCount  int                             // total number of detected test functions
+	testKindCounts map[testcase.TestKind]int       // number of detected test functions of each kind
+	nearMissCount  int                             // number of functions that look like test functions, but aren't valid ones
+	nearMissCounts map[testcase.NearMissReason]int // number of near misses with each reason
+	testFileCount  int                             // total number of files ending in "_test.go"
+	totalFileCount int                             // total number of Go files
+	totalTestLines int                             // total number of lines in all test functions
+	totalLines     int                             // total number of lines across the entire project
+
+	generatedFileCount int // total number of generated Go files (see `ast.IsGenerated`)
+	generatedLines     int // total number of lines in generated Go files
+
+	partial bool // whether parsing was interrupted before every file was visited
 }
 
 // Command-line flags for the Statistics command specifically
 type statisticsOptions struct {
 }
 
-// Compile-time interface implementation check
-var _ ParserCommand = (*StatisticsCommand)(nil)
+// Compile-time interface implementation checks
+var (
+	_ preparableCommand        = (*StatisticsCommand)(nil)
+	_ parser.CacheableTask     = (*StatisticsCommand)(nil)
+	_ parser.PartialTask       = (*StatisticsCommand)(nil)
+	_ parser.GeneratedFileTask = (*StatisticsCommand)(nil)
+	_ parser.SyntaxOnlyTask    = (*StatisticsCommand)(nil)
+	_ parser.SummaryTask       = (*StatisticsCommand)(nil)
+	_ parser.ReleasingTask     = (*StatisticsCommand)(nil)
+)
 
 // Register the command with the global flag parser
 func init() {
@@ -70,6 +88,96 @@ func (cmd *StatisticsCommand) Clone() parser.Task {
 	}
 }
 
+// Combine the partial results collected by another StatisticsCommand into this one.
+func (cmd *StatisticsCommand) Merge(other parser.Task) error {
+	o, ok := other.(*StatisticsCommand)
+	if !ok {
+		return fmt.Errorf("cannot merge %T into %T", other, cmd)
+	}
+	cmd.testCaseCount += o.testCaseCount
+	for kind, count := range o.testKindCounts {
+		cmd.countTestKind(kind, count)
+	}
+	cmd.nearMissCount += o.nearMissCount
+	for reason, count := range o.nearMissCounts {
+		cmd.countNearMiss(reason, count)
+	}
+	cmd.testFileCount += o.testFileCount
+	cmd.totalFileCount += o.totalFileCount
+	cmd.totalTestLines += o.totalTestLines
+	cmd.totalLines += o.totalLines
+	cmd.generatedFileCount += o.generatedFileCount
+	cmd.generatedLines += o.generatedLines
+	cmd.partial = cmd.partial || o.partial
+	return nil
+}
+
+// Clear the information cached while visiting the packages, so their memory can be reclaimed.
+func (cmd *StatisticsCommand) ReleasePackages() {
+	testcase.ReleaseCaches()
+}
+
+// Mark the collected results as incomplete because parsing was interrupted.
+func (cmd *StatisticsCommand) MarkPartial() {
+	cmd.partial = true
+}
+
+// Serializable representation of the results collected by a StatisticsCommand, used for caching.
+type statisticsResults struct {
+	TestCaseCount  int                             `json:"testCases"`
+	TestKindCounts map[testcase.TestKind]int       `json:"testKinds"`
+	NearMissCount  int                             `json:"nearMisses"`
+	NearMissCounts map[testcase.NearMissReason]int `json:"nearMissReasons"`
+	TestFileCount  int                             `json:"testFiles"`
+	TotalFileCount int                             `json:"totalFiles"`
+	TotalTestLines int                             `json:"testLines"`
+	TotalLines     int                             `json:"totalLines"`
+
+	GeneratedFileCount int `json:"generatedFiles"`
+	GeneratedLines     int `json:"generatedLines"`
+}
+
+// The statistics command has no options that affect its results.
+func (cmd *StatisticsCommand) CacheKey() string {
+	return ""
+}
+
+// Encode the counters collected by this instance of the command as JSON.
+func (cmd *StatisticsCommand) EncodeResults() ([]byte, error) {
+	return json.Marshal(statisticsResults{
+		TestCaseCount:  cmd.testCaseCount,
+		TestKindCounts: cmd.testKindCounts,
+		NearMissCount:  cmd.nearMissCount,
+		NearMissCounts: cmd.nearMissCounts,
+		TestFileCount:  cmd.testFileCount,
+		TotalFileCount: cmd.totalFileCount,
+		TotalTestLines: cmd.totalTestLines,
+		TotalLines:     cmd.totalLines,
+
+		GeneratedFileCount: cmd.generatedFileCount,
+		GeneratedLines:     cmd.generatedLines,
+	})
+}
+
+// Restore counters that were previously encoded using `EncodeResults`.
+func (cmd *StatisticsCommand) DecodeResults(data []byte) error {
+	var results statisticsResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return fmt.Errorf("decoding statistics results: %w", err)
+	}
+	cmd.testCaseCount = results.TestCaseCount
+	cmd.testKindCounts = results.TestKindCounts
+	cmd.nearMissCount = results.NearMissCount
+	cmd.nearMissCounts = results.NearMissCounts
+	cmd.testFileCount = results.TestFileCount
+	cmd.totalFileCount = results.TotalFileCount
+	cmd.totalTestLines = results.TotalTestLines
+	cmd.totalLines = results.TotalLines
+	cmd.generatedFileCount = results.GeneratedFileCount
+	cmd.generatedLines = results.GeneratedLines
+	return nil
+}
+
 // Set the project directory for this task.
 func (cmd *StatisticsCommand) SetProjectDir(dir string) {
 	cmd.globals.ProjectDir = dir
@@ -78,18 +186,27 @@ func (cmd *StatisticsCommand) SetProjectDir(dir string) {
 // Validate the values of this Command's flags, then run the task itself.
 // THIS SHOULD ONLY BE CALLED ONCE PER PROGRAM EXECUTION.
 func (cmd *StatisticsCommand) Execute(args []string) error {
+	if err := cmd.prepare(); err != nil {
+		return err
+	}
+
+	// Actually run the task by starting the parser
+	return parser.Parse(cmd.globals.Context, cmd, cmd.globals.ProjectDir, parserOptions(cmd.globals))
+}
+
+// Validate the values of this Command's flags and initialize the output writer, without starting the parser.
+func (cmd *StatisticsCommand) prepare() error {
 	if cmd.globals.OutputPath == "" {
 		cmd.globals.OutputPath = "statistics_report.csv"
 	}
 	// Initialize the output writer with the specified output path
 	writer, err := filewriter.NewFileWriter(cmd.globals.OutputPath, cmd.globals.AppendOutput)
 	if err != nil {
-		return fmt.Errorf("creating output writer for path %q", cmd.globals.OutputPath)
+		return fmt.Errorf("creating output writer for path %q: %w", cmd.globals.OutputPath, err)
 	}
 	cmd.output = writer
 
-	// Actually run the task by starting the parser
-	return parser.Parse(cmd, cmd.globals.ProjectDir, cmd.globals.SplitByDir, cmd.globals.Threads)
+	return nil
 }
 
 // Increment some numerical statistics about the project as a whole and its detected test cases
@@ -99,11 +216,11 @@ func (cmd *StatisticsCommand) Visit(file *ast.File, fset *token.FileSet, pkg *pa
 	fileName := fset.Position(file.FileStart).Filename
 
 	// increment project-scale statistics
-	cmd.totalFileCount++
-	if strings.HasSuffix(fileName, "_test.go") {
-		cmd.testFileCount++
-	}
-	cmd.totalLines += fset.Position(file.End()).Line - fset.Position(file.FileStart).Line + 1
+	cmd.countFile(file, fset, pkg)
+
+	// Find the testify suites whose methods are defined in this file
+	suites := testcase.FindSuites(file, pkg)
+	testFile := testcase.IsTestFile(file, fset)
 
 	// Only iterate top level declarations
 	for _, decl := range file.Decls {
@@ -114,30 +231,144 @@ func (cmd *StatisticsCommand) Visit(file *ast.File, fset *token.FileSet, pkg *pa
 
 		slog.Debug("Checking function...", "name", fn.Name.Name, "package", packageName, "file", fileName)
 
-		// Save the function as a valid test case if it meets all the criteria
-		valid, _ := testcase.IsValidTestCase(fn)
-		if !valid {
+		if !cmd.globals.Filter.MatchTest(fn.Name.Name) {
 			continue
 		}
 
-		tc := testcase.CreateTestCase(fn, file, pkg, projectName)
-		cmd.testCases = append(cmd.testCases, tc)
+		var tc testcase.TestCase
+		if suite := testcase.SuiteOf(fn, suites); suite != nil {
+			// Count methods of testify suites as test cases, even though they aren't test functions themselves
+			tc = testcase.CreateSuiteTestCase(fn, file, pkg, projectName, suite)
+		} else {
+			// Count functions that look like test functions but won't be run separately
+			if nm := testcase.FindNearMiss(fn, file, fset, projectName); nm != nil {
+				cmd.nearMissCount++
+				cmd.countNearMiss(nm.Reason, 1)
+				continue
+			}
+
+			// Save the function as a valid test case (of any kind) if it meets all the criteria and is in a test file
+			if _, valid, _ := testcase.ClassifyFunction(fn); !valid || !testFile {
+				continue
+			}
+			tc = testcase.CreateTestCase(fn, file, pkg, projectName)
+		}
+		cmd.testCaseCount++
+		cmd.countTestKind(tc.Kind, 1)
 
 		lines := tc.NumLines()
 		cmd.totalTestLines += lines
 	}
+
+	// Count each Ginkgo spec as a test case, since specs are defined inside the function literals passed to
+	// Ginkgo's container functions rather than as separate test functions
+	for _, spec := range testcase.FindGinkgoSpecs(file, pkg) {
+		if !cmd.globals.Filter.MatchTest(spec.FullText()) {
+			continue
+		}
+		tc := testcase.CreateGinkgoTestCase(spec, file, pkg, projectName)
+		cmd.testCaseCount++
+		cmd.countTestKind(tc.Kind, 1)
+		cmd.totalTestLines += tc.NumLines()
+	}
+}
+
+// Add to the number of detected test functions of the specified kind
+func (cmd *StatisticsCommand) countTestKind(kind testcase.TestKind, count int) {
+	if cmd.testKindCounts == nil {
+		cmd.testKindCounts = make(map[testcase.TestKind]int)
+	}
+	cmd.testKindCounts[kind] += count
+}
+
+// Add to the number of near misses with the specified reason
+func (cmd *StatisticsCommand) countNearMiss(reason testcase.NearMissReason, count int) {
+	if cmd.nearMissCounts == nil {
+		cmd.nearMissCounts = make(map[testcase.NearMissReason]int)
+	}
+	cmd.nearMissCounts[reason] += count
+}
+
+// Count the lines in a file from a package without test files, which doesn't need to be searched for test cases.
+func (cmd *StatisticsCommand) VisitSyntaxOnly(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	cmd.countFile(file, fset, pkg)
+}
+
+// Increment the project-scale statistics for a visited file
+func (cmd *StatisticsCommand) countFile(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	cmd.totalFileCount++
+	if strings.HasSuffix(fset.Position(file.FileStart).Filename, "_test.go") {
+		cmd.testFileCount++
+	}
+	cmd.totalLines += numFileLines(file, fset)
+
+	// Generated files are only visited if they're treated as regular files, but they're still counted separately
+	if ast.IsGenerated(file) {
+		cmd.VisitGenerated(file, fset, pkg)
+	}
+}
+
+// Count a generated file separately from the regular files in the project
+func (cmd *StatisticsCommand) VisitGenerated(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	cmd.generatedFileCount++
+	cmd.generatedLines += numFileLines(file, fset)
+}
+
+// Return the number of lines in a file
+func numFileLines(file *ast.File, fset *token.FileSet) int {
+	return fset.Position(file.End()).Line - fset.Position(file.FileStart).Line + 1
 }
 
 // Calculate some additional results and write everything to the output file
 func (cmd *StatisticsCommand) ReportResults() error {
+	reportLines, csvHeaders, row := cmd.formatResults(fmt.Sprintf("Statistics Report for %q", cmd.globals.ProjectDir))
+
+	// Print the report to the terminal
+	slog.Info("Finished running statistics task on project \"" + cmd.globals.ProjectDir + "\"")
+	fmt.Print(strings.Join(reportLines, "") + "\n")
+
+	// Append results to output file (text or CSV)
+	switch cmd.output.DetectFormat() {
+
+	case filewriter.FormatTxt:
+		return cmd.output.Write(reportLines)
+
+	case filewriter.FormatCSV:
+		return cmd.output.Write(row, csvHeaders)
+
+	default:
+		return fmt.Errorf("unsupported output format (file %q)", cmd.output.GetPath())
+	}
+}
+
+// Write the combined results of every unit after the project was split, recalculating the additional results
+// (like averages) from the combined totals.
+func (cmd *StatisticsCommand) ReportSummary(units int) error {
+	reportLines, csvHeaders, row := cmd.formatResults(fmt.Sprintf("Statistics Summary of %d Units in %q", units, cmd.globals.ProjectDir))
+	csvHeaders = slices.Insert(csvHeaders, 1, "units")
+	row = slices.Insert(row, 1, strconv.Itoa(units))
+
+	// Print the summary to the terminal
+	slog.Info("Finished running statistics task on every unit of project \"" + cmd.globals.ProjectDir + "\"")
+	fmt.Print(strings.Join(reportLines, "") + "\n")
+
+	return writeSummary(cmd.output, cmd.globals.AppendOutput, reportLines, csvHeaders, row)
+}
+
+// Format the collected results as the lines of a text report with the specified title, and as a CSV row with headers.
+func (cmd *StatisticsCommand) formatResults(title string) (reportLines, csvHeaders, row []string) {
 	// Format output for printing the report to the terminal (and potentially writing to a text file)
 
-	reportLines := []string{
-		fmt.Sprintf("\n=============  Statistics Report for %q:  =============\n\n", cmd.globals.ProjectDir),
+	reportLines = []string{
+		fmt.Sprintf("\n=============  %s:  =============\n\n", title),
+	}
+
+	if cmd.partial {
+		reportLines = append(reportLines, "WARNING: Parsing was interrupted, so these results are incomplete!\n\n")
 	}
 
 	// Define additional result statistics
-	numTests := len(cmd.testCases)
+	numTests := cmd.testCaseCount
 	avgTestLines := 0.0
 	percentTestLines := 0.0
 
@@ -148,8 +379,11 @@ func (cmd *StatisticsCommand) ReportResults() error {
 		avgTestLines = float64(cmd.totalTestLines) / float64(numTests)
 		percentTestLines = float64(cmd.totalTestLines) / float64(cmd.totalLines) * 100
 
+		reportLines = append(reportLines, fmt.Sprintf("Total number of test cases: %d\n", numTests))
+		for _, kind := range testcase.AllTestKinds() {
+			reportLines = append(reportLines, fmt.Sprintf("  - %s: %d\n", kind, cmd.testKindCounts[kind]))
+		}
 		reportLines = append(reportLines,
-			fmt.Sprintf("Total number of test cases: %d\n", numTests),
 			"\n",
 			fmt.Sprintf("Number of '_test.go' files: %d\n", cmd.testFileCount),
 			fmt.Sprintf("Total number of Go files: %d\n", cmd.totalFileCount),
@@ -161,42 +395,67 @@ func (cmd *StatisticsCommand) ReportResults() error {
 		)
 	}
 
-	// Print the report to the terminal
-	slog.Info("Finished running statistics task on project \"" + cmd.globals.ProjectDir + "\"")
-	fmt.Print(strings.Join(reportLines, "") + "\n")
-
-	// Append results to output file (text or CSV)
-	switch cmd.output.DetectFormat() {
-
-	case filewriter.FormatTxt:
-		return cmd.output.Write(reportLines)
-
-	case filewriter.FormatCSV:
-		csvHeaders := []string{
-			"projectDir",
-			"testCases",
-			"testFiles",
-			"totalFiles",
-			"testLines",
-			"avgLinesPerTest",
-			"percentTestLines",
+	if cmd.nearMissCount > 0 {
+		reportLines = append(reportLines, fmt.Sprintf("Near-miss test functions (which look like tests, but won't be run): %d\n", cmd.nearMissCount))
+		for _, reason := range testcase.AllNearMissReasons() {
+			if count := cmd.nearMissCounts[reason]; count > 0 {
+				reportLines = append(reportLines, fmt.Sprintf("  - %s (%s): %d\n", reason, reason.Description(), count))
+			}
 		}
+		reportLines = append(reportLines, "\n")
+	}
 
-		row := []string{
-			cmd.globals.ProjectDir,
-			fmt.Sprintf("%d", numTests),
-			fmt.Sprintf("%d", cmd.testFileCount),
-			fmt.Sprintf("%d", cmd.totalFileCount),
-			fmt.Sprintf("%d", cmd.totalTestLines),
-			fmt.Sprintf("%.1f", avgTestLines),
-			fmt.Sprintf("%.1f", percentTestLines),
+	if cmd.generatedFileCount > 0 {
+		treatment := "excluded from"
+		if cmd.globals.IncludeGenerated {
+			treatment = "included in"
 		}
+		reportLines = append(reportLines,
+			fmt.Sprintf("Generated files (%s the counts above): %d\n", treatment, cmd.generatedFileCount),
+			fmt.Sprintf("Lines in generated files: %d\n", cmd.generatedLines),
+			"\n",
+		)
+	}
 
-		return cmd.output.Write(row, csvHeaders)
-
-	default:
-		return fmt.Errorf("unsupported output format (file %q)", cmd.output.GetPath())
+	csvHeaders = []string{
+		"projectDir",
+		"testCases",
+	}
+	for _, kind := range testcase.AllTestKinds() {
+		csvHeaders = append(csvHeaders, kind.String()+"Functions")
+	}
+	csvHeaders = append(csvHeaders,
+		"nearMisses",
+		"testFiles",
+		"totalFiles",
+		"testLines",
+		"avgLinesPerTest",
+		"percentTestLines",
+		"generatedFiles",
+		"generatedLines",
+		"partial",
+	)
+
+	row = []string{
+		cmd.globals.ProjectDir,
+		fmt.Sprintf("%d", numTests),
+	}
+	for _, kind := range testcase.AllTestKinds() {
+		row = append(row, fmt.Sprintf("%d", cmd.testKindCounts[kind]))
 	}
+	row = append(row,
+		fmt.Sprintf("%d", cmd.nearMissCount),
+		fmt.Sprintf("%d", cmd.testFileCount),
+		fmt.Sprintf("%d", cmd.totalFileCount),
+		fmt.Sprintf("%d", cmd.totalTestLines),
+		fmt.Sprintf("%.1f", avgTestLines),
+		fmt.Sprintf("%.1f", percentTestLines),
+		fmt.Sprintf("%d", cmd.generatedFileCount),
+		fmt.Sprintf("%d", cmd.generatedLines),
+		strconv.FormatBool(cmd.partial),
+	)
+
+	return reportLines, csvHeaders, row
 }
 
 // Close the output file writer
@@ -0,0 +1,164 @@
+package parser
+
+// Handles loading and visiting packages in bounded batches, which limits the memory used when parsing huge projects.
+
+import (
+	"cmp"
+	"context"
+	"go/token"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/maxgreen01/go-test-parser/pkg/progress"
+	"golang.org/x/tools/go/packages"
+)
+
+// The batch size used when a memory limit is specified without a batch size
+const DefaultBatchSize = 32
+
+// The information loaded when listing packages before loading them in batches, which doesn't require parsing any files
+const listMode = packages.NeedName | packages.NeedFiles | packages.NeedForTest
+
+// Lists the packages matching `pattern` in the specified directory, and then loads and visits them in batches of
+// at most `opts.BatchSize` packages using `workers` goroutines. Each batch is visited before the next one is loaded,
+// and nothing refers to its syntax or type information afterward, so only a single batch is held in memory at once.
+// If `opts.MemoryLimit` is set, the batch size is halved whenever loading a batch uses more memory than the limit,
+// and doubled again (up to `opts.BatchSize`) once memory usage falls well below it.
+// Returns whether any packages were found.
+func (r *parseRun) visitBatches(ctx context.Context, task Task, dir, pattern string, unit *progress.Unit, workers int) (bool, error) {
+	// List the packages with each build configuration, estimating the amount of work based on their files
+	configs := r.buildConfigs()
+	listed := make([][]*packages.Package, len(configs)) // the packages listed using each build configuration
+	counted := make(map[string]bool)                    // file paths that were already counted for another build configuration
+	total, numVariants, numFiles := 0, 0, 0
+	for i, config := range configs {
+		mode := listMode
+		if !r.opts.LoadAllPackages {
+			mode = testPackagesListMode
+		}
+		pkgs, err := loadPackages(ctx, dir, []string{pattern}, token.NewFileSet(), config, mode, nil)
+		if err != nil {
+			return false, err
+		}
+		listed[i] = pkgs
+		total += len(listedImportPaths(pkgs, nil))
+		variants, files := r.estimateVisits(pkgs, counted)
+		numVariants += variants
+		numFiles += files
+	}
+	if total == 0 {
+		return false, nil
+	}
+	unit.Loaded(numVariants, numFiles)
+	slog.Info("Loading packages in batches", "dir", dir, "packages", total, "batchSize", r.opts.BatchSize)
+
+	// Load and visit each batch of packages
+	selected := make(map[string]bool) // file paths that were already selected from another batch or build configuration
+	batchSize := r.opts.BatchSize
+	for i, config := range configs {
+		// Only parse the packages without test files, unless every package should be loaded
+		paths := listedImportPaths(listed[i], nil)
+		if !r.opts.LoadAllPackages {
+			var untested []syntaxOnlyPackage
+			paths, untested = r.splitTestPackages(listed[i], selected)
+			if err := r.visitSyntaxOnly(ctx, task, unit, untested); err != nil {
+				return true, err
+			}
+		}
+
+		for len(paths) > 0 {
+			batch := paths[:min(batchSize, len(paths))]
+			paths = paths[len(batch):]
+
+			var parseFile parseFileFunc
+			if !r.opts.LoadAllPackages {
+				parseFile = dependencyParser(listed[i], batch)
+			}
+			fset := token.NewFileSet()
+			pkgs, err := loadPackages(ctx, dir, batch, fset, config, loadMode, parseFile)
+			if err != nil {
+				return true, err
+			}
+			batchSize = r.adjustBatchSize(batchSize)
+
+			variants := r.filterVariants(excludeSelectedFiles(selectPackageVariants(pkgs, fset), fset, selected, config), fset)
+			slog.Debug("Visiting batch of packages", "dir", dir, "packages", len(batch), "variants", len(variants), "remainingPackages", len(paths))
+			err = r.visitPackages(ctx, task, dir, unit, variants, fset, workers)
+			releasePackages(task) // nothing may refer to the batch once it's visited
+			if err != nil {
+				return true, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// Return the sorted import paths of the listed packages, excluding test variants and test executables,
+// since loading a package with tests also loads those. If `keep` isn't nil, only the import paths it contains are returned.
+func listedImportPaths(pkgs []*packages.Package, keep map[string]bool) []string {
+	var paths []string
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" || isTestMain(pkg) || strings.Contains(pkg.ID, " ") {
+			continue
+		}
+		if keep != nil && !keep[pkg.PkgPath] {
+			continue
+		}
+		paths = append(paths, pkg.PkgPath)
+	}
+	slices.Sort(paths)
+	return slices.Compact(paths)
+}
+
+// Estimate the number of package variants and files that will be visited using the listed packages, by assigning
+// their files to variants in the same way as selectPackageVariants (except without the parsed syntax), and skipping
+// files rejected by the filter or already counted from another build configuration.
+func (r *parseRun) estimateVisits(pkgs []*packages.Package, counted map[string]bool) (variants, files int) {
+	candidates := slices.DeleteFunc(slices.Clone(pkgs), isTestMain)
+	slices.SortStableFunc(candidates, func(a, b *packages.Package) int {
+		return cmp.Or(
+			cmp.Compare(len(b.GoFiles), len(a.GoFiles)),
+			cmp.Compare(len(a.Errors), len(b.Errors)),
+			strings.Compare(a.ID, b.ID),
+		)
+	})
+
+	for _, pkg := range candidates {
+		found := false
+		for _, filePath := range pkg.GoFiles {
+			if counted[filePath] || !r.matchFile(filePath, pkg.PkgPath) {
+				continue
+			}
+			counted[filePath] = true
+			files++
+			found = true
+		}
+		if found {
+			variants++
+		}
+	}
+	return variants, files
+}
+
+// Return the size of the next batch, based on how much memory is being used after loading the current batch.
+// Always returns the configured batch size if there's no memory limit.
+func (r *parseRun) adjustBatchSize(batchSize int) int {
+	if r.opts.MemoryLimit <= 0 {
+		return batchSize
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	used := int64(stats.HeapAlloc)
+	switch {
+	case used > r.opts.MemoryLimit && batchSize > 1:
+		slog.Warn("Memory usage exceeded the limit, so fewer packages will be loaded at once", "usedMiB", used>>20, "limitMiB", r.opts.MemoryLimit>>20, "batchSize", batchSize/2)
+		return batchSize / 2
+	case used < r.opts.MemoryLimit/2 && batchSize < r.opts.BatchSize:
+		slog.Debug("Memory usage is well below the limit, so more packages will be loaded at once", "usedMiB", used>>20, "limitMiB", r.opts.MemoryLimit>>20, "batchSize", batchSize*2)
+		return min(batchSize*2, r.opts.BatchSize)
+	}
+	return batchSize
+}
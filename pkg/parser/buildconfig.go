@@ -0,0 +1,102 @@
+package parser
+
+// Handles loading packages using several build configurations (i.e. build tags and target platforms),
+// so that files excluded by the default configuration can still be visited.
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Represents a build configuration that packages can be loaded with.
+// The zero value represents the default configuration, i.e. no extra build tags and the host platform.
+type BuildConfig struct {
+	Tags   []string // extra build tags to enable, like the `-tags` flag of `go build`
+	GOOS   string   // the target operating system, or empty to use the default
+	GOARCH string   // the target architecture, or empty to use the default
+}
+
+// Return every combination of the specified tag sets and platforms, in order.
+// Each tag set is a comma- or space-separated list of build tags, and each platform is formatted like `GOOS/GOARCH`.
+// Returns nil (i.e. only the default configuration should be used) if neither tags nor platforms are specified.
+func BuildMatrix(tagSets, platforms []string) ([]BuildConfig, error) {
+	if len(tagSets) == 0 && len(platforms) == 0 {
+		return nil, nil
+	}
+
+	// Use the default tags and platform if only the other dimension was specified
+	tags := make([][]string, 0, len(tagSets))
+	for _, set := range tagSets {
+		tags = append(tags, strings.FieldsFunc(set, func(r rune) bool {
+			return r == ',' || r == ' '
+		}))
+	}
+	if len(tags) == 0 {
+		tags = append(tags, nil)
+	}
+	type platform struct{ goos, goarch string }
+	targets := make([]platform, 0, len(platforms))
+	for _, p := range platforms {
+		goos, goarch, ok := strings.Cut(strings.TrimSpace(p), "/")
+		if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+			return nil, fmt.Errorf("invalid platform %q, expected GOOS/GOARCH (e.g. linux/amd64)", p)
+		}
+		targets = append(targets, platform{goos, goarch})
+	}
+	if len(targets) == 0 {
+		targets = append(targets, platform{})
+	}
+
+	configs := make([]BuildConfig, 0, len(targets)*len(tags))
+	for _, target := range targets {
+		for _, set := range tags {
+			configs = append(configs, BuildConfig{Tags: set, GOOS: target.goos, GOARCH: target.goarch})
+		}
+	}
+	return configs, nil
+}
+
+// Return a string representation of the configuration, like `linux/arm64 tags=integration`.
+func (c BuildConfig) String() string {
+	platform := "default"
+	if c.GOOS != "" || c.GOARCH != "" {
+		platform = orDefault(c.GOOS) + "/" + orDefault(c.GOARCH)
+	}
+	if len(c.Tags) == 0 {
+		return platform
+	}
+	return platform + " tags=" + strings.Join(c.Tags, ",")
+}
+
+// Return the value, or "default" if it's empty.
+func orDefault(value string) string {
+	if value == "" {
+		return "default"
+	}
+	return value
+}
+
+// Return the build flags that should be passed to the build system when loading packages with this configuration.
+func (c BuildConfig) buildFlags() []string {
+	if len(c.Tags) == 0 {
+		return nil
+	}
+	return []string{"-tags=" + strings.Join(c.Tags, ",")}
+}
+
+// Return the environment that should be used when loading packages with this configuration,
+// or nil to use the environment of the current process.
+func (c BuildConfig) env() []string {
+	if c.GOOS == "" && c.GOARCH == "" {
+		return nil
+	}
+	env := os.Environ()
+	if c.GOOS != "" {
+		env = append(env, "GOOS="+c.GOOS)
+	}
+	if c.GOARCH != "" {
+		env = append(env, "GOARCH="+c.GOARCH)
+	}
+	return env
+}
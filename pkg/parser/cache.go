@@ -0,0 +1,265 @@
+package parser
+
+// Handles the on-disk cache of per-package task results, which allows unchanged packages to be skipped on later runs.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Optional interface for tasks whose per-package results can be saved to the cache and restored on later runs.
+// Each cached entry is produced by a fresh clone of the task that has visited exactly one package, and restoring
+// an entry decodes it into another fresh clone that is then merged into the running task using `Merge`.
+type CacheableTask interface {
+	Task
+
+	// Return a string representing every option that affects the task's results (e.g. flags),
+	// so that results produced with different options are cached separately
+	CacheKey() string
+
+	// Serialize the results collected by this instance of the task
+	EncodeResults() ([]byte, error)
+
+	// Restore results that were previously serialized using `EncodeResults`, replacing any existing results
+	DecodeResults(data []byte) error
+}
+
+// Returns the task as a CacheableTask if it supports caching.
+// Task groups only support caching if every task in the group does.
+func asCacheable(t Task) (CacheableTask, bool) {
+	if group, ok := t.(*taskGroup); ok {
+		for _, task := range group.tasks {
+			if _, ok := asCacheable(task); !ok {
+				return nil, false
+			}
+		}
+	}
+	ct, ok := t.(CacheableTask)
+	return ct, ok
+}
+
+// Stores the encoded results of tasks in a directory, keyed by a hash of each package's contents.
+// Entries are located at `<dir>/<task name>/<first 2 chars of key>/<key>.json`.
+type resultCache struct {
+	dir         string
+	toolVersion string
+	optionsKey  string // represents the parser options that affect the results of every task
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// Creates a result cache in the specified directory, creating the directory if it doesn't already exist.
+// The `optionsKey` should represent every parser option that affects which files are visited.
+func newResultCache(dir, optionsKey string) (*resultCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating cache directory %q: %w", dir, err)
+	}
+	slog.Info("Using result cache", "dir", dir)
+	return &resultCache{dir: dir, toolVersion: toolVersion(), optionsKey: optionsKey}, nil
+}
+
+// Computes the cache key for the results of running a task on the specified package variant.
+// The key covers the tool version, the parser and task options, the project directory, the package's identity,
+// the contents of its module's `go.mod` file, the contents of every source file in the package, and the contents of
+// every source file in the local packages it depends on (see localDependencies). Because source files are read from
+// disk, any change to a package or its local dependencies produces a different key. Other dependencies are only
+// covered by `go.mod`, since their contents are determined by their versions.
+func (c *resultCache) key(t CacheableTask, dir string, variant packageVariant, fset *token.FileSet) (string, error) {
+	h := sha256.New()
+	writeField := func(s string) {
+		// Separate fields with a NUL byte so different field boundaries can't produce the same hash
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+
+	pkg := variant.pkg
+	writeField(c.toolVersion)
+	writeField(c.optionsKey)
+	writeField(t.Name())
+	writeField(t.CacheKey())
+	writeField(dir)
+	writeField(pkg.ID)
+
+	// Include the module definition, since dependency versions affect type information
+	if pkg.Module != nil && pkg.Module.GoMod != "" {
+		if err := hashFile(h, pkg.Module.GoMod); err != nil {
+			return "", err
+		}
+	}
+
+	// Include every file in the package (not just the visited ones) since they all affect type information
+	files := slices.Clone(pkg.CompiledGoFiles)
+	slices.Sort(files)
+	for _, file := range files {
+		writeField(file)
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+
+	// Include the files of the local dependencies, since they affect type information and the results of running tests
+	for _, dep := range localDependencies(pkg) {
+		writeField(dep.ID)
+		files := slices.Clone(dep.CompiledGoFiles)
+		slices.Sort(files)
+		for _, file := range files {
+			writeField(file)
+			if err := hashFile(h, file); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	// Include the files that are actually visited, since the same package can be visited with different subsets of its files
+	for _, file := range variant.files {
+		writeField(fset.Position(file.FileStart).Filename)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns the packages that the package imports (directly or indirectly) from the main module, or from another module
+// that is replaced by a local directory, sorted by ID. Packages are only searched for imports if they're local too.
+func localDependencies(pkg *packages.Package) []*packages.Package {
+	seen := make(map[string]*packages.Package)
+	var visit func(p *packages.Package)
+	visit = func(p *packages.Package) {
+		for _, imported := range p.Imports {
+			if seen[imported.ID] != nil || !isLocalModule(imported.Module) {
+				continue
+			}
+			seen[imported.ID] = imported
+			visit(imported)
+		}
+	}
+	visit(pkg)
+	delete(seen, pkg.ID)
+
+	deps := slices.Collect(maps.Values(seen))
+	slices.SortFunc(deps, func(a, b *packages.Package) int { return strings.Compare(a.ID, b.ID) })
+	return deps
+}
+
+// Returns whether the module's source files are in a local directory that may be edited, i.e. it's the main module
+// or it's replaced by a directory (which has no version).
+func isLocalModule(module *packages.Module) bool {
+	if module == nil {
+		return false
+	}
+	return module.Main || (module.Replace != nil && module.Replace.Version == "")
+}
+
+// Writes the contents of the file at the specified path to the writer, followed by a NUL byte.
+func hashFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("hashing file %q: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("hashing file %q: %w", path, err)
+	}
+	_, err = w.Write([]byte{0})
+	return err
+}
+
+// Returns the path of the cache entry for the specified task and key.
+func (c *resultCache) entryPath(taskName, key string) string {
+	return filepath.Join(c.dir, taskName, key[:2], key+".json")
+}
+
+// Returns the data stored in the cache for the specified task and key, and whether the entry was found.
+func (c *resultCache) load(taskName, key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.entryPath(taskName, key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("Cannot read cache entry", "err", err, "task", taskName, "key", key)
+		}
+		return nil, false
+	}
+	return data, true
+}
+
+// Saves data to the cache for the specified task and key.
+// The data is written to a temporary file and then renamed, so concurrent readers never see a partial entry.
+func (c *resultCache) store(taskName, key string, data []byte) error {
+	path := c.entryPath(taskName, key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// Logs the number of packages that were restored from the cache and the number that had to be parsed.
+// Does nothing if the cache is nil (i.e. caching is disabled).
+func (c *resultCache) logStatistics() {
+	if c == nil {
+		return
+	}
+	slog.Info("Result cache statistics", "restoredPackages", c.hits.Load(), "parsedPackages", c.misses.Load())
+}
+
+// Returns a string identifying the build of this program, so that cached results are invalidated whenever it changes.
+// Uses the module version and VCS information embedded by the Go toolchain, falling back to the executable's size and
+// modification time for development builds without VCS information or with uncommitted changes.
+func toolVersion() string {
+	version := "unknown"
+	exact := false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				exact = true
+				version += " " + setting.Key + "=" + setting.Value
+			case "vcs.modified":
+				exact = exact && setting.Value != "true"
+				version += " " + setting.Key + "=" + setting.Value
+			}
+		}
+	}
+	if exact {
+		return version
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		if stat, err := os.Stat(exe); err == nil {
+			version += fmt.Sprintf(" exe=%d@%d", stat.Size(), stat.ModTime().UnixNano())
+		}
+	}
+	return version
+}
@@ -0,0 +1,161 @@
+package parser
+
+// Handles filtering which files and tests are parsed, based on glob patterns and regular expressions.
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Selects which packages, files, and tests are parsed.
+//
+// Include and exclude patterns are globs (see `path.Match`) in which a "**" element matches any number of path elements.
+// Patterns containing a "/" are matched against a file's slash-separated path relative to the project directory,
+// against each of its parent directories, and against the import path of its package. Patterns without a "/" are
+// matched against each individual element of the relative path, i.e. the file name and the name of each parent directory.
+//
+// A file is parsed if it matches any include pattern (or there are no include patterns), and it doesn't match
+// any exclude pattern. Test cases are also only parsed if their names match the test pattern, if it's specified.
+// Calling methods on a nil Filter matches everything.
+type Filter struct {
+	include     []string
+	exclude     []string
+	testPattern *regexp.Regexp // nil if every test should be parsed
+}
+
+// Create a Filter using the specified glob patterns and test name regular expression, which are validated.
+// Returns nil if no filtering is requested.
+func NewFilter(include, exclude []string, testPattern string) (*Filter, error) {
+	var f Filter
+	var err error
+	if f.include, err = cleanPatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = cleanPatterns(exclude); err != nil {
+		return nil, err
+	}
+	if testPattern != "" {
+		re, err := regexp.Compile(testPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid test name pattern %q: %w", testPattern, err)
+		}
+		f.testPattern = re
+	}
+
+	if len(f.include) == 0 && len(f.exclude) == 0 && f.testPattern == nil {
+		return nil, nil
+	}
+	return &f, nil
+}
+
+// Normalize the glob patterns to use slashes without a trailing slash, removing empty patterns
+// and returning an error if any pattern is malformed.
+func cleanPatterns(patterns []string) ([]string, error) {
+	var cleaned []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(pattern)), "/")
+		if pattern == "" {
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		cleaned = append(cleaned, pattern)
+	}
+	return cleaned, nil
+}
+
+// Return whether the file should be parsed, given its path relative to the project directory and its package's import path.
+func (f *Filter) MatchFile(relPath, pkgPath string) bool {
+	if f == nil {
+		return true
+	}
+	relPath = filepath.ToSlash(relPath)
+	if len(f.include) > 0 && !f.matchAny(f.include, relPath, pkgPath) {
+		return false
+	}
+	return !f.matchAny(f.exclude, relPath, pkgPath)
+}
+
+// Return whether every file in the directory is excluded, given the directory's path relative to the project directory.
+// Used to avoid loading packages that would be skipped anyway.
+func (f *Filter) ExcludesDir(relDir string) bool {
+	if f == nil || relDir == "." {
+		return false
+	}
+	return f.matchAny(f.exclude, filepath.ToSlash(relDir), "")
+}
+
+// Return whether the test with the specified name should be parsed.
+func (f *Filter) MatchTest(name string) bool {
+	return f == nil || f.testPattern == nil || f.testPattern.MatchString(name)
+}
+
+// Return a string representing every pattern used by the filter, for use in cache keys.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	test := ""
+	if f.testPattern != nil {
+		test = f.testPattern.String()
+	}
+	return fmt.Sprintf("include=%q,exclude=%q,run=%q", f.include, f.exclude, test)
+}
+
+// Return whether any of the patterns match the file's relative path or its package's import path.
+func (f *Filter) matchAny(patterns []string, relPath, pkgPath string) bool {
+	elements := strings.Split(relPath, "/")
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			// Match any single element of the path
+			for _, elem := range elements {
+				if ok, _ := path.Match(pattern, elem); ok {
+					return true
+				}
+			}
+			continue
+		}
+
+		// Match the path itself or any of its parent directories, or the package's import path
+		for i := range elements {
+			if matchGlob(pattern, elements[:i+1]) {
+				return true
+			}
+		}
+		if pkgPath != "" && matchGlob(pattern, strings.Split(pkgPath, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return whether the path elements match the slash-separated glob pattern, where a "**" element matches
+// any number of path elements (including none).
+func matchGlob(pattern string, elements []string) bool {
+	return matchElements(strings.Split(pattern, "/"), elements)
+}
+
+func matchElements(pattern, elements []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elements); i++ {
+				if matchElements(pattern[1:], elements[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elements) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], elements[0]); !ok {
+			return false
+		}
+		pattern, elements = pattern[1:], elements[1:]
+	}
+	return len(elements) == 0
+}
@@ -0,0 +1,86 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"exact path", "pkg/parser/filter.go", "pkg/parser/filter.go", true},
+		{"different path", "pkg/parser/filter.go", "pkg/parser/split.go", false},
+		{"wildcard element", "pkg/*/filter.go", "pkg/parser/filter.go", true},
+		{"wildcard doesn't cross slashes", "pkg/*.go", "pkg/parser/filter.go", false},
+		{"pattern longer than path", "pkg/parser/*.go", "pkg/parser", false},
+		{"path longer than pattern", "pkg/parser", "pkg/parser/filter.go", false},
+		{"double star matches no elements", "pkg/**/filter.go", "pkg/filter.go", true},
+		{"double star matches one element", "pkg/**/filter.go", "pkg/parser/filter.go", true},
+		{"double star matches several elements", "pkg/**/filter.go", "pkg/a/b/c/filter.go", true},
+		{"leading double star", "**/filter.go", "pkg/parser/filter.go", true},
+		{"leading double star matches at root", "**/filter.go", "filter.go", true},
+		{"trailing double star", "pkg/**", "pkg/parser/filter.go", true},
+		{"trailing double star matches the directory itself", "pkg/**", "pkg", true},
+		{"trailing double star needs its prefix", "pkg/**", "internal/pkg/filter.go", false},
+		{"double star followed by mismatch", "pkg/**/split.go", "pkg/parser/filter.go", false},
+		{"several double stars", "**/testdata/**/*.go", "a/b/testdata/c/d/e.go", true},
+		{"several double stars without match", "**/testdata/**/*.go", "a/b/data/c/d/e.go", false},
+		{"double star only matches whole elements", "pkg/**x/filter.go", "pkg/parser/filter.go", false},
+		{"character class", "pkg/[ps]arser/*.go", "pkg/parser/filter.go", true},
+		{"import path", "github.com/*/go-test-parser/**", "github.com/maxgreen01/go-test-parser/pkg/parser", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, strings.Split(tt.path, "/")); got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterMatchFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		relPath string
+		pkgPath string
+		want    bool
+	}{
+		{"no patterns", nil, nil, "a/b_test.go", "example.com/a", true},
+		{"include by file name", []string{"*_test.go"}, nil, "a/b_test.go", "example.com/a", true},
+		{"include by file name without match", []string{"*_test.go"}, nil, "a/b.go", "example.com/a", false},
+		{"include by directory name", []string{"internal"}, nil, "x/internal/y/z.go", "example.com/x/internal/y", true},
+		{"include by parent directory", []string{"pkg/parser"}, nil, "pkg/parser/filter.go", "example.com/pkg/parser", true},
+		{"include by import path", []string{"example.com/*/parser"}, nil, "parser/filter.go", "example.com/pkg/parser", true},
+		{"exclude by directory name", nil, []string{"testdata"}, "a/testdata/b.go", "example.com/a/testdata", false},
+		{"exclude with double star", nil, []string{"**/gen/**"}, "a/gen/b/c.go", "example.com/a/gen/b", false},
+		{"exclude wins over include", []string{"a/**"}, []string{"*_gen.go"}, "a/b_gen.go", "example.com/a", false},
+		{"exclude without match", nil, []string{"vendor"}, "a/b.go", "example.com/a", true},
+		{"trailing slash is ignored", nil, []string{"a/b/"}, "a/b/c.go", "example.com/a/b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFilter(tt.include, tt.exclude, "")
+			if err != nil {
+				t.Fatalf("NewFilter(%q, %q) returned error: %v", tt.include, tt.exclude, err)
+			}
+			if got := f.MatchFile(tt.relPath, tt.pkgPath); got != tt.want {
+				t.Errorf("MatchFile(%q, %q) = %v, want %v", tt.relPath, tt.pkgPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFilterInvalidPattern(t *testing.T) {
+	if _, err := NewFilter([]string{"a/[b"}, nil, ""); err == nil {
+		t.Error("NewFilter accepted a malformed glob pattern")
+	}
+	if _, err := NewFilter(nil, nil, "Test("); err == nil {
+		t.Error("NewFilter accepted a malformed test name pattern")
+	}
+}
@@ -0,0 +1,56 @@
+package parser
+
+// Handles reporting the results of units in a consistent order, regardless of the order in which they finish.
+
+import (
+	"sync"
+)
+
+// Calls the report function of each unit in the order of the units' indices, so that the reports of units parsed
+// concurrently are always written to a shared output in the same order. Each unit's report is delayed until every
+// previous unit has finished, and reports are never called concurrently.
+type orderedReports struct {
+	mu      sync.Mutex
+	next    int            // the index of the next unit to report
+	pending map[int]func() // the report functions of finished units that can't be called yet, or nil if there's nothing to report
+}
+
+func newOrderedReports() *orderedReports {
+	return &orderedReports{pending: make(map[int]func())}
+}
+
+// Record that the unit with the specified index has finished, calling its report function (unless it's nil) along with
+// the report functions of any later units that were waiting for it, as long as every previous unit has also finished.
+func (o *orderedReports) done(index int, report func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending[index] = report
+	for {
+		report, ok := o.pending[o.next]
+		if !ok {
+			return
+		}
+		delete(o.pending, o.next)
+		o.next++
+		if report != nil {
+			report()
+		}
+	}
+}
+
+// Call the report functions of every finished unit that is still waiting for a previous unit, in order.
+// Used after all units have stopped, since units that were never started (e.g. because the parser was interrupted)
+// never finish.
+func (o *orderedReports) flush() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for len(o.pending) > 0 {
+		if report, ok := o.pending[o.next]; ok {
+			delete(o.pending, o.next)
+			if report != nil {
+				report()
+			}
+		}
+		o.next++
+	}
+}
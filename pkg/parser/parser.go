@@ -8,10 +8,11 @@ import (
 	"go/ast"
 	"go/token"
 	"log/slog"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/maxgreen01/go-test-parser/pkg/progress"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/tools/go/packages"
 )
@@ -27,9 +28,14 @@ type Task interface {
 	Visit(file *ast.File, fset *token.FileSet, pkg *packages.Package)
 
 	// Create a new instance of the task with the same initial state and flags.
-	// Used to ensure that each parsed directory can have an independent output if `splitByDir` is true.
+	// Used to ensure that each parsed unit can have an independent output when splitting the project,
+	// and to give each worker its own partial state when visiting packages concurrently.
 	Clone() Task
 
+	// Combine the partial results collected by another instance of the same task (usually created using Clone)
+	// into this instance. Called before `ReportResults` after packages were visited by multiple workers.
+	Merge(other Task) error
+
 	// Set the project directory for this task. Often used after Clone to set the directory for the new instance.
 	SetProjectDir(dir string)
 
@@ -41,81 +47,248 @@ type Task interface {
 	Close()
 }
 
+// Optional interface for tasks that can indicate in their reports that the results are incomplete.
+// If parsing is interrupted, `MarkPartial` is called before `ReportResults` is called with the results collected so far.
+type PartialTask interface {
+	Task
+
+	// Mark the results collected by this instance of the task as incomplete
+	MarkPartial()
+}
+
+// Optional interface for tasks that want to know about generated files (i.e. files with a "Code generated ... DO NOT EDIT."
+// comment), which are skipped by the parser unless `IncludeGenerated` is set.
+type GeneratedFileTask interface {
+	Task
+
+	// Function called instead of `Visit` on every generated file that is skipped
+	VisitGenerated(file *ast.File, fset *token.FileSet, pkg *packages.Package)
+}
+
+// Optional interface for tasks that can report a combined summary of the entire project when it's split into units
+// (see `Options.SplitBy`). After every unit has reported its own results, the results of every unit are merged into
+// the original task, and `ReportSummary` is called in addition to the per-unit reports.
+type SummaryTask interface {
+	Task
+
+	// Report the combined results of the specified number of units, which were merged into this instance of the task
+	ReportSummary(units int) error
+}
+
+// Optional interface for tasks that keep caches referring to the syntax or type information of visited packages.
+// After every package loaded at once (i.e. a batch, or an entire directory) has been visited, `ReleasePackages` is called
+// so the caches can be cleared before the next packages are loaded, allowing the memory of the old ones to be reclaimed.
+type ReleasingTask interface {
+	Task
+
+	// Drop every reference to the syntax and type information of the packages that were visited so far
+	ReleasePackages()
+}
+
+// Mark the task's results as incomplete if it implements PartialTask.
+func markPartial(t Task) {
+	if pt, ok := t.(PartialTask); ok {
+		pt.MarkPartial()
+	}
+}
+
+// Release the task's references to the visited packages if it implements ReleasingTask.
+func releasePackages(t Task) {
+	if rt, ok := t.(ReleasingTask); ok {
+		rt.ReleasePackages()
+	}
+}
+
+// Configuration options that control how the parser loads and visits packages.
+type Options struct {
+	// How to split the project into units that are parsed separately, each producing its own report
+	SplitBy SplitMode
+
+	// The number of concurrent threads to use for parsing units (when splitting the project) or visiting packages
+	Threads int
+
+	// Whether to visit files that have errors (e.g. unresolved imports or type errors) instead of skipping them.
+	// Type information for these files may be incomplete, so tasks should be prepared for missing types.
+	IncludeErrorFiles bool
+
+	// The build configurations (i.e. build tags and target platforms) used to load packages, in order of priority.
+	// Each file is visited once, using the first configuration that includes it, so tests that are excluded by the
+	// default configuration (e.g. because of a `//go:build integration` constraint) can still be visited.
+	// Only the default configuration is used if this is empty.
+	BuildConfigs []BuildConfig
+
+	// Whether to load and fully type-check every package, including packages without test files and every dependency.
+	// Otherwise, the packages are listed first so that only packages with test files are loaded and fully type-checked,
+	// dependencies are type-checked without their function bodies, and the files of other packages are only parsed
+	// (see SyntaxOnlyTask).
+	LoadAllPackages bool
+
+	// The maximum number of packages to load at once, or 0 to load every package in a directory at once.
+	// When set, packages are listed first and then loaded and visited in batches, so only the syntax and type
+	// information of a single batch is held in memory at once. This is slower (since shared dependencies are loaded
+	// again for each batch), but allows huge projects to be parsed with a bounded amount of memory.
+	BatchSize int
+
+	// Soft limit for the heap memory used while loading packages in batches, in bytes, or 0 for no limit.
+	// The batch size is reduced whenever loading a batch exceeds this limit. Has no effect if `BatchSize` is 0.
+	MemoryLimit int64
+
+	// Whether to visit generated files like regular files instead of skipping them. See GeneratedFileTask.
+	IncludeGenerated bool
+
+	// Directory where the per-package results of tasks implementing CacheableTask are stored between runs,
+	// allowing packages whose contents haven't changed to be skipped. Caching is disabled if this is empty.
+	CacheDir string
+
+	// Tracker used to report the progress of the parser, or nil if progress shouldn't be reported
+	Progress *progress.Tracker
+
+	// Selects which files are visited, or nil to visit every file. Vendored files are always skipped.
+	// Tasks are responsible for applying the filter's test name pattern themselves.
+	Filter *Filter
+}
+
+// Runs several tasks on all Go source files in the given directory using a single package-loading pass.
+// Every visited file is passed to each task in order, and each task reports its own results to its own output.
+// See Parse for details about the other parameters.
+func ParseAll(ctx context.Context, tasks []Task, rootDir string, opts Options) error {
+	if len(tasks) == 0 {
+		return errors.New("no tasks provided")
+	}
+	if slices.Contains(tasks, nil) {
+		return errors.New("nil task provided")
+	}
+	if len(tasks) == 1 {
+		return Parse(ctx, tasks[0], rootDir, opts)
+	}
+	return Parse(ctx, &taskGroup{tasks: tasks}, rootDir, opts)
+}
+
 // Runs the specified task on all Go source files in the given directory.
-// If `splitByDir` is true, parses each top-level directory in the specified directory separately (ignoring top-level Go files).
-// todo maybe update the Task interface to include a method for getting flags (to avoid passing so many boilerplate params)
-func Parse(t Task, rootDir string, splitByDir bool, threads int) error {
+// If `opts.SplitBy` is set, splits the project into units (e.g. top-level directories or modules) that are parsed separately,
+// using `opts.Threads` goroutines to parse units concurrently. Otherwise, `opts.Threads` workers are used to visit the
+// packages of the entire directory concurrently.
+//
+// If `ctx` is canceled (e.g. because the user interrupted the program), no new packages or units are started,
+// but files that are already being visited are allowed to finish. The results collected so far are then reported
+// as partial results (see PartialTask), and an error wrapping the context's error is returned.
+func Parse(ctx context.Context, t Task, rootDir string, opts Options) error {
 	if rootDir == "" {
 		return errors.New("empty root directory provided")
 	}
 	if t == nil {
 		return errors.New("nil task provided")
 	}
+	threads := max(opts.Threads, 1)
+
+	r := &parseRun{opts: opts, rootDir: rootDir}
+	if opts.CacheDir != "" {
+		if _, ok := asCacheable(t); !ok {
+			slog.Warn("Caching is not supported by this task, so all packages will be parsed", "task", t.Name())
+		} else {
+			cache, err := newResultCache(opts.CacheDir, fmt.Sprintf("includeErrorFiles=%t,includeGenerated=%t,filter=%s,buildConfigs=%q", opts.IncludeErrorFiles, opts.IncludeGenerated, opts.Filter, opts.BuildConfigs))
+			if err != nil {
+				return fmt.Errorf("opening result cache: %w", err)
+			}
+			r.cache = cache
+		}
+	}
 
 	fmt.Println()
 	slog.Info("============ Running " + t.Name() + " task on project \"" + rootDir + "\" ============")
 	fmt.Println()
 
-	// Run the parser either on the entire directory at once, or on each top-level sub-directory separately
-	if splitByDir {
-		// Parse each top-level directory separately
-		slog.Info("Parsing each top-level directory separately")
-
-		entries, err := os.ReadDir(rootDir)
+	// Run the parser either on the entire directory at once, or on each unit of the project separately
+	if opts.SplitBy != SplitNone {
+		units, err := splitProject(rootDir, opts.SplitBy)
 		if err != nil {
-			return err
+			return fmt.Errorf("splitting project by %s: %w", opts.SplitBy, err)
+		}
+		units = slices.DeleteFunc(units, func(unit parseUnit) bool {
+			relDir, err := filepath.Rel(rootDir, unit.dir)
+			if err == nil && opts.Filter.ExcludesDir(relDir) {
+				slog.Info("Skipping unit excluded by filter", "dir", unit.dir)
+				return true
+			}
+			return false
+		})
+		if len(units) == 0 {
+			slog.Warn("Nothing to parse after splitting project directory "+rootDir, "splitBy", opts.SplitBy)
+			return nil // No files to process, so just return
 		}
+		// Sort the units so their reports are always written in the same order
+		slices.SortStableFunc(units, func(a, b parseUnit) int {
+			return strings.Compare(a.dir, b.dir)
+		})
+		slog.Info(fmt.Sprintf("Parsing %d units of the project separately", len(units)), "splitBy", opts.SplitBy)
+		opts.Progress.Start(rootDir, len(units))
+		defer opts.Progress.Stop()
 
-		var foundDir bool
 		// Define concurrency control variables
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
 		g, gctx := errgroup.WithContext(ctx)
 		g.SetLimit(threads) // Limit the number of concurrent goroutines to avoid overwhelming the system
 		slog.Info("Using " + fmt.Sprint(threads) + " threads for parsing")
 
-		for _, entry := range entries {
-			if entry.IsDir() {
-				foundDir = true
-
-				// Start a new goroutine for each subdirectory
-				g.Go(func() error {
-					// Clone the Task instance so each parsing run has a distinct output but uses the same underlying resources
-					newTask := t.Clone()
-
-					// Check for cancellation before doing any work
-					select {
-					case <-gctx.Done():
-						return gctx.Err()
-					default:
-					}
-
-					// Parse the subdirectory
-					subDir := filepath.Join(rootDir, entry.Name())
-					if err := parseDir(gctx, newTask, subDir); err != nil {
-						return fmt.Errorf("parsing subdirectory %q: %w", subDir, err)
-					}
-					return nil
-				})
-			}
-		}
-		if !foundDir {
-			slog.Warn("No subdirectories found in project directory " + rootDir)
-			return nil // No files to process, so just return
+		unitTasks := make([]Task, len(units)) // the task used for each unit, or nil if the unit was never started
+		reports := newOrderedReports()
+		for i, unit := range units {
+			// Start a new goroutine for each unit
+			g.Go(func() error {
+				// Report the unit's results once every previous unit has been reported, even if this unit fails
+				var report func()
+				defer func() { reports.done(i, report) }()
+
+				// Clone the Task instance so each parsing run has a distinct output but uses the same underlying resources
+				newTask := t.Clone()
+
+				// Check for cancellation before doing any work
+				select {
+				case <-gctx.Done():
+					return gctx.Err()
+				default:
+				}
+				unitTasks[i] = newTask
+
+				// Parse the unit
+				// Each unit is already parsed concurrently, so its packages are visited by a single worker
+				found, err := r.parseDir(gctx, newTask, unit.dir, unit.pattern, 1)
+				if found {
+					report = func() { r.reportResults(newTask) }
+				}
+				if err != nil {
+					return fmt.Errorf("parsing directory %q: %w", unit.dir, err)
+				}
+				return nil
+			})
 		}
 
 		// Wait for all the goroutines to finish
-		if err := g.Wait(); err != nil {
-			return err
+		err = g.Wait()
+		reports.flush()
+		if err != nil {
+			if ctx.Err() != nil {
+				r.reportSummary(t, unitTasks, true)
+			}
+			return r.stop(ctx, t, err)
 		}
+		r.reportSummary(t, unitTasks, false)
 	} else {
-		// Parse the entire directory as a single unit
-		if err := parseDir(context.Background(), t, rootDir); err != nil {
-			return err
+		// Parse the entire directory as a single unit, visiting its packages concurrently
+		slog.Info("Using " + fmt.Sprint(threads) + " threads for visiting packages")
+		opts.Progress.Start(rootDir, 1)
+		defer opts.Progress.Stop()
+		found, err := r.parseDir(ctx, t, rootDir, "./...", threads)
+		if found {
+			r.reportResults(t)
+		}
+		if err != nil {
+			return r.stop(ctx, t, err)
 		}
 	}
 
 	// Successfully parsed all directories and files
+	opts.Progress.Stop()
+	r.cache.logStatistics()
 	fmt.Println()
 	slog.Info("Finished running the parser!", "task", t.Name(), "project", rootDir)
 	fmt.Println()
@@ -127,99 +300,436 @@ func Parse(t Task, rootDir string, splitByDir bool, threads int) error {
 	return nil
 }
 
-// Iterates over all Go source files in the specified directory and runs the provided task on each file.
-// After processing all files, calls the task's ReportResults method to output any accumulated results.
-// todo MAYBE make this multithreaded even without `splitByDir` somehow
-func parseDir(ctx context.Context, task Task, dir string) error {
+// Holds the configuration and state shared by every directory parsed during a single call to Parse.
+type parseRun struct {
+	opts    Options
+	rootDir string       // the project directory, which filter patterns are relative to
+	cache   *resultCache // nil if caching is disabled
+}
+
+// Handle an error that stopped the parser early. If the parser was interrupted, the task is closed so that any
+// partial results that were already reported are saved, and the returned error explains that parsing was interrupted.
+// Otherwise, the error is returned unchanged.
+func (r *parseRun) stop(ctx context.Context, t Task, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	r.opts.Progress.Stop()
+	r.cache.logStatistics()
+	slog.Warn("Parser was interrupted before finishing, so only partial results were reported", "task", t.Name())
+	t.Close()
+	return fmt.Errorf("parsing interrupted: %w", ctx.Err())
+}
+
+// Iterates over all Go source files in the packages matching `pattern` in the specified directory and runs the provided task on each file.
+// Packages are distributed between `workers` goroutines, each of which visits files using its own clone of the task.
+// After processing all files, the clones are merged back into the original task.
+// Returns whether the task's results should be reported, which is also the case if parsing was interrupted after
+// some packages were found. The caller is responsible for reporting them (see reportResults), so that the reports
+// of several units can be written in a consistent order.
+func (r *parseRun) parseDir(ctx context.Context, task Task, dir, pattern string, workers int) (bool, error) {
 	// Check for cancellation before starting
 	select {
 	case <-ctx.Done():
-		return ctx.Err()
+		return false, ctx.Err()
 	default:
 	}
 
 	task.SetProjectDir(dir)
+	unit := r.opts.Progress.StartUnit(dir)
 
+	resume := r.opts.Progress.Suspend()
 	fmt.Println()
 	fmt.Println()
 	slog.Info("~~~~~ Parsing directory \"" + dir + "\" ~~~~~")
+	resume()
 
-	fset := token.NewFileSet()
-	cfg := &packages.Config{
-		Mode:  packages.LoadAllSyntax | packages.NeedForTest,
-		Dir:   dir,
-		Fset:  fset,
-		Tests: true, // Load test files as well
+	// ========== Load and visit all packages ==========
+	var found bool
+	var err error
+	if r.opts.BatchSize > 0 {
+		found, err = r.visitBatches(ctx, task, dir, pattern, unit, workers)
+	} else {
+		found, err = r.visitAll(ctx, task, dir, pattern, unit, workers)
 	}
-
-	// Construct a pattern to load all packages in the specified directory and its subdirectories,
-	// first removing all trailing forward slashes or backslashes to ensure a valid pattern
-	pattern := strings.TrimRight(dir, "/\\") + "/..."
-	pkgs, err := packages.Load(cfg, pattern)
 	if err != nil {
-		return fmt.Errorf("loading packages in directory %q: %w", dir, err)
+		if ctx.Err() == nil {
+			return false, err
+		}
+		// Interrupted, so report whatever was collected before stopping
+		slog.Warn("Parsing interrupted, reporting partial results", "dir", dir)
+		markPartial(task)
+		unit.Finish()
+		return true, err
 	}
-	if len(pkgs) == 0 {
+	if !found {
 		// todo maybe this should be an error?
 		slog.Warn("No packages found in directory " + dir)
-		return nil // No packages to process, so just return
+		return false, nil // No packages to process, so just return
+	}
+
+	// finished iterating without problem
+	slog.Info("Finished parsing all source files in directory", "dir", dir)
+	unit.Finish()
+	return true, nil
+}
+
+// Loads every package matching `pattern` in the specified directory at once, and then visits them using `workers` goroutines.
+// Returns whether any packages were found.
+func (r *parseRun) visitAll(ctx context.Context, task Task, dir, pattern string, unit *progress.Unit, workers int) (bool, error) {
+	// Load the packages matching the pattern (which is relative to the directory) using each build configuration,
+	// choosing which variant of each package to use for every file so no file is visited more than once
+	fset := token.NewFileSet()
+	var variants []packageVariant
+	var untested []syntaxOnlyPackage
+	selected := make(map[string]bool) // file paths that were already selected from another build configuration
+	loaded := 0
+	for _, config := range r.buildConfigs() {
+		// List the packages first to find the ones with test files, unless every package should be loaded
+		patterns := []string{pattern}
+		var parseFile parseFileFunc
+		if !r.opts.LoadAllPackages {
+			listed, err := loadPackages(ctx, dir, patterns, token.NewFileSet(), config, testPackagesListMode, nil)
+			if err != nil {
+				return false, err
+			}
+			loaded += len(listed)
+			var configUntested []syntaxOnlyPackage
+			patterns, configUntested = r.splitTestPackages(listed, selected)
+			untested = append(untested, configUntested...)
+			if len(patterns) == 0 {
+				continue
+			}
+			parseFile = dependencyParser(listed, patterns)
+		}
+
+		pkgs, err := loadPackages(ctx, dir, patterns, fset, config, loadMode, parseFile)
+		if err != nil {
+			return false, err
+		}
+		loaded += len(pkgs)
+
+		// todo note: consider walking the import graph to analyze imported functions -- maybe cache these to avoid re-analyzing them?
+		//    could probably use the `packages.Visit` function's pre- and post-visit hooks to modify a map
+		//    maybe should do the entire iterating like this, where all results of flattening non-test functions are stored in a map?
+		//    Currently functions are only expanded within the same package, but this might be useful for cross-package expansion
+
+		variants = append(variants, excludeSelectedFiles(selectPackageVariants(pkgs, fset), fset, selected, config)...)
+	}
+	if loaded == 0 {
+		return false, nil
+	}
+
+	variants = r.filterVariants(variants, fset)
+	numFiles := 0
+	for _, variant := range variants {
+		numFiles += len(variant.files)
+	}
+	for _, p := range untested {
+		numFiles += len(p.files)
+	}
+	unit.Loaded(len(variants)+len(untested), numFiles)
+
+	if err := r.visitSyntaxOnly(ctx, task, unit, untested); err != nil {
+		return true, err
+	}
+	err := r.visitPackages(ctx, task, dir, unit, variants, fset, workers)
+	releasePackages(task)
+	return true, err
+}
+
+// Return the build configurations used to load packages, which is only the default configuration if none are specified.
+func (r *parseRun) buildConfigs() []BuildConfig {
+	if len(r.opts.BuildConfigs) == 0 {
+		return []BuildConfig{{}}
 	}
+	return r.opts.BuildConfigs
+}
+
+// The information loaded for every package that is visited
+const loadMode = packages.LoadAllSyntax | packages.NeedForTest | packages.NeedModule
 
-	// todo note: consider walking the import graph to analyze imported functions -- maybe cache these to avoid re-analyzing them?
-	//    could probably use the `packages.Visit` function's pre- and post-visit hooks to modify a map
-	//    maybe should do the entire iterating like this, where all results of flattening non-test functions are stored in a map?
-	//    Currently functions are only expanded within the same package, but this might be useful for cross-package expansion
+// Function used to parse the source files of loaded packages (see `packages.Config.ParseFile`)
+type parseFileFunc = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error)
+
+// Load the packages matching `patterns` in the specified directory using the build configuration and load mode,
+// parsing files using `parseFile` if it isn't nil.
+// Syntax for all packages is added to the same FileSet, so it can be shared between build configurations.
+func loadPackages(ctx context.Context, dir string, patterns []string, fset *token.FileSet, config BuildConfig, mode packages.LoadMode, parseFile parseFileFunc) ([]*packages.Package, error) {
+	cfg := &packages.Config{
+		Mode:       mode,
+		Dir:        dir,
+		Fset:       fset,
+		Tests:      true, // Load test files as well
+		Context:    ctx,  // Stop loading if the parser is interrupted
+		BuildFlags: config.buildFlags(),
+		Env:        config.env(),
+		ParseFile:  parseFile,
+	}
 
-	// ========== Iterate over all top-level packages ==========
-	for _, pkg := range pkgs {
-		pkgErrs := pkg.Errors
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("loading packages in directory %q with build configuration %q: %w", dir, config, err)
+	}
+	slog.Debug("Loaded packages", "dir", dir, "config", config, "packages", len(pkgs))
+	return pkgs, nil
+}
 
-		// Build a "set" of filepaths that have errors in this package before iterating files
-		errFiles := make(map[string]struct{}, len(pkgErrs))
-		for _, e := range pkgErrs {
-			// Print every error in the package
-			slog.Error("Error in package:", "error", e.Msg, "package", pkg.Name, "position", e.Pos)
+// Remove the files rejected by the filter from every package variant, dropping variants that have no files left.
+func (r *parseRun) filterVariants(variants []packageVariant, fset *token.FileSet) []packageVariant {
+	if r.opts.Filter == nil {
+		return variants
+	}
 
-			colonIdx := strings.Index(e.Pos, ":")
-			if colonIdx > 0 {
-				file := e.Pos[:colonIdx]
-				errFiles[file] = struct{}{}
+	filtered := make([]packageVariant, 0, len(variants))
+	skipped := 0
+	for _, variant := range variants {
+		var files []*ast.File
+		for _, file := range variant.files {
+			filePath := fset.Position(file.FileStart).Filename
+			if !r.matchFile(filePath, variant.pkg.PkgPath) {
+				slog.Debug("Skipping file rejected by filter", "file", filePath)
+				skipped++
+				continue
 			}
+			files = append(files, file)
+		}
+		if len(files) > 0 {
+			filtered = append(filtered, packageVariant{pkg: variant.pkg, files: files})
+		}
+	}
+
+	slog.Info("Filtered files to visit", "skippedFiles", skipped, "remainingVariants", len(filtered))
+	return filtered
+}
+
+// Return whether the filter accepts the file, given its absolute path and its package's import path.
+func (r *parseRun) matchFile(filePath, pkgPath string) bool {
+	if r.opts.Filter == nil {
+		return true
+	}
+	relPath, err := filepath.Rel(r.rootDir, filePath)
+	if err != nil {
+		relPath = filePath
+	}
+	return r.opts.Filter.MatchFile(relPath, pkgPath)
+}
+
+// Call the task's ReportResults method after the unit is finished, hiding the progress display while the report is printed.
+func (r *parseRun) reportResults(task Task) {
+	resume := r.opts.Progress.Suspend()
+	defer resume()
+	if err := task.ReportResults(); err != nil {
+		slog.Error("Error reporting task results", "err", err)
+	}
+}
+
+// Merge the results of every unit that was started into the original task and report them as a summary of the
+// entire project, if the task implements SummaryTask. The progress display is hidden while the summary is printed.
+func (r *parseRun) reportSummary(t Task, unitTasks []Task, interrupted bool) {
+	st, ok := t.(SummaryTask)
+	if !ok {
+		return
+	}
+	t.SetProjectDir(r.rootDir)
+	units := 0
+	for _, unitTask := range unitTasks {
+		if unitTask == nil {
+			continue
 		}
+		if err := t.Merge(unitTask); err != nil {
+			slog.Error("Error combining the results of every unit", "err", err)
+			return
+		}
+		units++
+	}
+	if interrupted {
+		markPartial(t)
+	}
 
-		// ========== Iterate over all files in the package ==========
-		for _, file := range pkg.Syntax {
-			// Check for cancellation before processing each file
+	resume := r.opts.Progress.Suspend()
+	defer resume()
+	if err := st.ReportSummary(units); err != nil {
+		slog.Error("Error reporting summary of task results", "err", err)
+	}
+}
+
+// Visits every package variant using a pool of `workers` goroutines. Each worker visits files using its own clone
+// of the task, and all the clones are merged back into the original task (in worker order) once every package has been visited.
+// If only one worker is requested, the packages are visited directly by the original task instead.
+// If the context is canceled, the partial results of every worker are still merged into the original task.
+func (r *parseRun) visitPackages(ctx context.Context, task Task, dir string, unit *progress.Unit, variants []packageVariant, fset *token.FileSet, workers int) error {
+	if workers <= 1 || len(variants) <= 1 {
+		for _, variant := range variants {
+			if err := r.visitPackageCached(ctx, task, dir, unit, variant, fset); err != nil {
+				return err
+			}
+			unit.PackageDone()
+		}
+		return nil
+	}
+	workers = min(workers, len(variants))
+
+	// Feed packages to the workers through a channel, stopping early if any worker fails
+	g, gctx := errgroup.WithContext(ctx)
+	queue := make(chan packageVariant)
+	g.Go(func() error {
+		defer close(queue)
+		for _, variant := range variants {
 			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
+			case queue <- variant:
+			case <-gctx.Done():
+				return gctx.Err()
 			}
+		}
+		return nil
+	})
+
+	// Start the workers, each with an independent clone of the task to store partial results
+	clones := make([]Task, workers)
+	for i := range clones {
+		clone := task.Clone()
+		clone.SetProjectDir(dir)
+		clones[i] = clone
+
+		g.Go(func() error {
+			for variant := range queue {
+				if err := r.visitPackageCached(gctx, clone, dir, unit, variant, fset); err != nil {
+					return err
+				}
+				unit.PackageDone()
+			}
+			return nil
+		})
+	}
 
-			filePath := fset.Position(file.FileStart).Filename
+	// Unless parsing was interrupted, stop without merging since the results won't be reported
+	waitErr := g.Wait()
+	if waitErr != nil && ctx.Err() == nil {
+		return waitErr
+	}
 
-			// Skip files in `vendor/` directory
-			if strings.Contains(filePath, filepath.Join("vendor", "")) {
-				slog.Debug("Skipping vendored file", "file", filePath)
-				continue
-			}
+	// Combine the partial results of every worker
+	for _, clone := range clones {
+		if err := task.Merge(clone); err != nil {
+			return fmt.Errorf("merging partial %s task results: %w", task.Name(), err)
+		}
+	}
+	return waitErr
+}
+
+// Runs the provided task on the specified package variant, restoring the results from the cache instead if the package
+// hasn't changed since they were saved. Otherwise, the package is visited by a separate clone of the task so that its
+// results can be saved to the cache before being merged into the provided task.
+// If caching is disabled, the package is simply visited by the provided task.
+func (r *parseRun) visitPackageCached(ctx context.Context, task Task, dir string, unit *progress.Unit, variant packageVariant, fset *token.FileSet) error {
+	if r.cache == nil {
+		return r.visitPackage(ctx, task, unit, variant, fset)
+	}
+	ct, _ := asCacheable(task) // Support for caching is already checked by Parse
+
+	key, err := r.cache.key(ct, dir, variant, fset)
+	if err != nil {
+		slog.Warn("Cannot compute cache key for package, so it will be parsed normally", "err", err, "package", variant.pkg.ID)
+		return r.visitPackage(ctx, task, unit, variant, fset)
+	}
+
+	// Try restoring the package's results from the cache
+	if data, found := r.cache.load(ct.Name(), key); found {
+		partial := ct.Clone().(CacheableTask)
+		partial.SetProjectDir(dir)
+		if err := partial.DecodeResults(data); err != nil {
+			slog.Warn("Cannot decode cached results for package, so it will be parsed normally", "err", err, "package", variant.pkg.ID)
+		} else {
+			slog.Debug("Restored cached results for package", "package", variant.pkg.ID)
+			r.cache.hits.Add(1)
+			unit.FilesDone(len(variant.files))
+			return task.Merge(partial)
+		}
+	}
+	r.cache.misses.Add(1)
+
+	// Visit the package using a separate instance of the task so only this package's results are cached
+	partial := ct.Clone().(CacheableTask)
+	partial.SetProjectDir(dir)
+	if err := r.visitPackage(ctx, partial, unit, variant, fset); err != nil {
+		// Don't cache incomplete results, but keep them in case partial results are reported
+		if mergeErr := task.Merge(partial); mergeErr != nil {
+			return errors.Join(err, mergeErr)
+		}
+		return err
+	}
+	if data, err := partial.EncodeResults(); err != nil {
+		slog.Warn("Cannot encode package results for caching", "err", err, "package", variant.pkg.ID)
+	} else if err := r.cache.store(ct.Name(), key, data); err != nil {
+		slog.Warn("Cannot save package results to cache", "err", err, "package", variant.pkg.ID)
+	}
+	return task.Merge(partial)
+}
+
+// Runs the provided task on every file selected for the specified package variant, skipping vendored files.
+// Files with errors and generated files are also skipped unless `IncludeErrorFiles` and `IncludeGenerated` are set.
+func (r *parseRun) visitPackage(ctx context.Context, task Task, unit *progress.Unit, variant packageVariant, fset *token.FileSet) error {
+	pkg := variant.pkg
+	pkgErrs := pkg.Errors
+
+	// Build a "set" of filepaths that have errors in this package before iterating files
+	errFiles := make(map[string]struct{}, len(pkgErrs))
+	for _, e := range pkgErrs {
+		// Print every error in the package
+		slog.Error("Error in package:", "error", e.Msg, "package", pkg.Name, "position", e.Pos)
+
+		colonIdx := strings.Index(e.Pos, ":")
+		if colonIdx > 0 {
+			file := e.Pos[:colonIdx]
+			errFiles[file] = struct{}{}
+		}
+	}
+
+	// ========== Iterate over all files in the package ==========
+	for _, file := range variant.files {
+		// Check for cancellation before processing each file
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 
-			// Skip files that have errors
-			if _, found := errFiles[filePath]; found {
+		filePath := fset.Position(file.FileStart).Filename
+
+		// Skip files in `vendor/` directory
+		if strings.Contains(filePath, filepath.Join("vendor", "")) {
+			slog.Debug("Skipping vendored file", "file", filePath)
+			unit.FilesDone(1) // Count skipped files too, so the progress reaches 100%
+			continue
+		}
+
+		// Skip files that have errors, unless they should be visited with incomplete type information
+		if _, found := errFiles[filePath]; found {
+			if !r.opts.IncludeErrorFiles {
 				slog.Info("Skipping file with errors", "file", filePath)
+				unit.FilesDone(1)
 				continue
 			}
+			slog.Info("Visiting file with errors using incomplete type information", "file", filePath)
+		}
 
-			// Actually process the file
-			// slog.Debug("Processing file", "package", pkg.Name, "file", filePath)
-			task.Visit(file, fset, pkg)
+		// Skip generated files, but still let the task count them separately
+		if !r.opts.IncludeGenerated && ast.IsGenerated(file) {
+			slog.Debug("Skipping generated file", "file", filePath)
+			if gt, ok := task.(GeneratedFileTask); ok {
+				gt.VisitGenerated(file, fset, pkg)
+			}
+			unit.FilesDone(1)
+			continue
 		}
-	}
 
-	// finished iterating without problem
-	slog.Info("Finished parsing all source files in directory", "dir", dir)
-	if err := task.ReportResults(); err != nil {
-		slog.Error("Error reporting task results", "err", err)
+		// Actually process the file
+		// slog.Debug("Processing file", "package", pkg.Name, "file", filePath)
+		task.Visit(file, fset, pkg)
+		unit.FilesDone(1)
 	}
-	return nil
+	// The last file may have been cut short by an interruption (e.g. a test execution being stopped), so its results
+	// shouldn't be treated as complete
+	return ctx.Err()
 }
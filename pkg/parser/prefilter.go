@@ -0,0 +1,216 @@
+package parser
+
+// Handles finding the packages that contain tests before loading them, so that packages without tests
+// don't have to be type-checked.
+
+import (
+	"context"
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/maxgreen01/go-test-parser/pkg/progress"
+	"golang.org/x/tools/go/packages"
+)
+
+// Optional interface for tasks that want to visit the files of packages without any test files, which are only parsed
+// (without being type-checked) unless `LoadAllPackages` is set. Tasks that don't implement this interface skip them.
+type SyntaxOnlyTask interface {
+	Task
+
+	// Function called instead of `Visit` on every file in a package without test files. The file has no type information,
+	// and `pkg` only contains the package's name, import path, and files.
+	VisitSyntaxOnly(file *ast.File, fset *token.FileSet, pkg *packages.Package)
+}
+
+// The information loaded when listing packages to find the ones with test files. The imports of every dependency
+// are included so that the dependencies' files can be parsed without their function bodies (see dependencyParser).
+const testPackagesListMode = listMode | packages.NeedImports | packages.NeedDeps
+
+// Represents a package without any test files, along with the files that should be visited using only their syntax.
+type syntaxOnlyPackage struct {
+	pkg   *packages.Package
+	files []string
+}
+
+// Split the listed packages into the sorted import paths of the packages with test files, which must be loaded
+// and type-checked, and the packages without test files, which are only parsed. Files that were already selected
+// from another build configuration are removed from the packages without test files, along with files rejected by
+// the filter, and the remaining files are added to `selected`.
+func (r *parseRun) splitTestPackages(pkgs []*packages.Package, selected map[string]bool) (tested []string, untested []syntaxOnlyPackage) {
+	// Packages with test files are the only ones with test variants
+	hasTests := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" {
+			hasTests[pkg.ForTest] = true
+		}
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" || isTestMain(pkg) || strings.Contains(pkg.ID, " ") {
+			continue
+		}
+		if hasTests[pkg.PkgPath] {
+			continue
+		}
+		var files []string
+		for _, filePath := range pkg.GoFiles {
+			if selected[filePath] || !r.matchFile(filePath, pkg.PkgPath) {
+				continue
+			}
+			selected[filePath] = true
+			files = append(files, filePath)
+		}
+		if len(files) > 0 {
+			untested = append(untested, syntaxOnlyPackage{pkg: pkg, files: files})
+		}
+	}
+
+	slog.Info("Found packages with test files", "testPackages", len(hasTests), "otherPackages", len(untested))
+	return listedImportPaths(pkgs, hasTests), untested
+}
+
+// Parse the files of packages without test files (without type-checking them), and visit them using the task if it
+// implements SyntaxOnlyTask. Generated files are skipped in the same way as when visiting packages normally.
+func (r *parseRun) visitSyntaxOnly(ctx context.Context, task Task, unit *progress.Unit, untested []syntaxOnlyPackage) error {
+	st, ok := task.(SyntaxOnlyTask)
+	for _, p := range untested {
+		// Check for cancellation before processing each package
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		if !ok {
+			unit.FilesDone(len(p.files))
+			unit.PackageDone()
+			continue
+		}
+
+		fset := token.NewFileSet()
+		for _, filePath := range p.files {
+			file, err := goparser.ParseFile(fset, filePath, nil, goparser.ParseComments|goparser.SkipObjectResolution)
+			switch {
+			case err != nil:
+				slog.Info("Skipping file with syntax errors", "file", filePath, "err", err)
+			case !r.opts.IncludeGenerated && ast.IsGenerated(file):
+				slog.Debug("Skipping generated file", "file", filePath)
+				if gt, ok := task.(GeneratedFileTask); ok {
+					gt.VisitGenerated(file, fset, p.pkg)
+				}
+			default:
+				st.VisitSyntaxOnly(file, fset, p.pkg)
+			}
+			unit.FilesDone(1)
+		}
+		unit.PackageDone()
+	}
+	return nil
+}
+
+// Return a function for `packages.Config.ParseFile` that parses the files of the packages with the specified import
+// paths (including their test files) normally, but removes the function bodies from the files of every other package,
+// which are only dependencies. Type-checking dependencies without their function bodies is much faster and uses
+// much less memory, but still provides complete type information for the packages that are visited.
+// The imports of every dependency's files are resolved using the listed packages.
+func dependencyParser(listed []*packages.Package, visited []string) parseFileFunc {
+	keep := make(map[string]bool, len(visited))
+	for _, path := range visited {
+		keep[path] = true
+	}
+	owners := make(map[string]*packages.Package) // maps file paths to the package that contains them
+	packages.Visit(listed, nil, func(pkg *packages.Package) {
+		for _, filePath := range pkg.GoFiles {
+			owners[filePath] = pkg
+		}
+	})
+
+	return func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		// Use the same mode as the default parser used by `packages.Load`
+		file, err := goparser.ParseFile(fset, filename, src, goparser.AllErrors|goparser.ParseComments)
+		pkg := owners[filename]
+		if file == nil || pkg == nil || keep[pkg.PkgPath] || keep[pkg.ForTest] {
+			return file, err
+		}
+		stripFunctionBodies(file, pkg)
+		return file, err
+	}
+}
+
+// Remove the function bodies from a dependency's file, since only its declarations are needed to type-check
+// the packages that import it. Imports that are no longer used are replaced with blank imports to avoid type errors.
+// Files whose imports can't all be resolved (e.g. because of dot imports or cgo) are left unchanged.
+func stripFunctionBodies(file *ast.File, pkg *packages.Package) {
+	// Find the local name of every import, using the name of the imported package if the import isn't renamed
+	imports := make(map[string]*ast.ImportSpec, len(file.Imports))
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || path == "C" {
+			return
+		}
+		name := ""
+		if spec.Name != nil {
+			name = spec.Name.Name
+		} else if imported := pkg.Imports[path]; imported != nil {
+			name = imported.Name
+		}
+		if name == "" || name == "." {
+			return
+		}
+		if name != "_" {
+			imports[name] = spec
+		}
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil || isGenericFunc(fn) {
+			continue // Generic functions must have a body
+		}
+		if fn.Recv == nil && fn.Name.Name == "init" {
+			// `init` functions must have a body
+			fn.Body = &ast.BlockStmt{Lbrace: fn.Body.Lbrace, Rbrace: fn.Body.Rbrace}
+			continue
+		}
+		fn.Body = nil
+	}
+
+	// Blank out the imports that are only used by the removed function bodies
+	used := make(map[string]bool)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	for name, spec := range imports {
+		if !used[name] {
+			spec.Name = ast.NewIdent("_")
+		}
+	}
+}
+
+// Return whether the function has type parameters, or is a method of a generic type.
+func isGenericFunc(fn *ast.FuncDecl) bool {
+	if fn.Type.TypeParams != nil {
+		return true
+	}
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return false
+	}
+	recv := fn.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+	switch recv.(type) {
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return true
+	}
+	return false
+}
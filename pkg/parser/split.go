@@ -0,0 +1,265 @@
+package parser
+
+// Handles splitting a project into separate units (e.g. modules or packages) that are each parsed independently.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Represents the way a project is split into separately-parsed units, each of which produces its own report.
+type SplitMode int
+
+const (
+	SplitNone      SplitMode = iota // Parse the entire project as a single unit
+	SplitByDir                      // Parse each top-level directory separately, ignoring top-level Go files
+	SplitByModule                   // Parse each Go module separately, including nested modules and `go.work` members
+	SplitByPackage                  // Parse each package directory separately
+)
+
+// Return the SplitMode corresponding to the given string.
+func SplitModeFromString(mode string) SplitMode {
+	switch strings.ToLower(mode) {
+	case "none", "":
+		return SplitNone
+	case "dir":
+		return SplitByDir
+	case "module":
+		return SplitByModule
+	case "package":
+		return SplitByPackage
+	default:
+		slog.Warn("Unknown split mode", "mode", mode)
+		return SplitNone
+	}
+}
+
+// Return the string representation of the SplitMode
+func (m SplitMode) String() string {
+	switch m {
+	case SplitByDir:
+		return "dir"
+	case SplitByModule:
+		return "module"
+	case SplitByPackage:
+		return "package"
+	default:
+		return "none"
+	}
+}
+
+// Represents a part of a project that is loaded and parsed on its own.
+type parseUnit struct {
+	dir     string // the directory used as `packages.Config.Dir`, which is also the task's project directory
+	pattern string // the package pattern to load, relative to `dir`
+}
+
+// Split the project in the specified directory into the units that should be parsed separately.
+func splitProject(rootDir string, mode SplitMode) ([]parseUnit, error) {
+	switch mode {
+	case SplitNone:
+		return []parseUnit{{dir: rootDir, pattern: "./..."}}, nil
+
+	case SplitByDir:
+		entries, err := os.ReadDir(rootDir)
+		if err != nil {
+			return nil, err
+		}
+		var units []parseUnit
+		for _, entry := range entries {
+			if entry.IsDir() {
+				units = append(units, parseUnit{dir: filepath.Join(rootDir, entry.Name()), pattern: "./..."})
+			}
+		}
+		return units, nil
+
+	case SplitByModule:
+		modules, err := findModules(rootDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(modules) == 0 {
+			// Not a module-aware project (or it's inside a parent module), so treat the entire directory as the only module
+			return []parseUnit{{dir: rootDir, pattern: "./..."}}, nil
+		}
+		units := make([]parseUnit, len(modules))
+		for i, module := range modules {
+			// The `./...` pattern never matches packages in nested modules, so each package is only loaded once
+			units[i] = parseUnit{dir: module, pattern: "./..."}
+		}
+		return units, nil
+
+	case SplitByPackage:
+		modules, err := findModules(rootDir)
+		if err != nil {
+			return nil, err
+		}
+		if len(modules) == 0 {
+			// Not a module-aware project, so treat the entire directory as the only "module"
+			modules = []string{rootDir}
+		}
+		var units []parseUnit
+		for _, module := range modules {
+			dirs, err := findPackageDirs(module)
+			if err != nil {
+				return nil, err
+			}
+			for _, dir := range dirs {
+				units = append(units, parseUnit{dir: dir, pattern: "."})
+			}
+		}
+		return units, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported split mode %q", mode)
+	}
+}
+
+// Find the root directories of every Go module in the specified directory, sorted by path.
+// If the directory is part of a Go workspace (i.e. a `go.work` file applies to it), the workspace's members inside
+// the directory are used. Otherwise, every directory containing a `go.mod` file is treated as a module.
+func findModules(rootDir string) ([]string, error) {
+	members, err := workspaceModules(rootDir)
+	if err != nil {
+		return nil, err
+	}
+	if members != nil {
+		var modules []string
+		for _, member := range members {
+			if !isWithinDir(member, rootDir) {
+				slog.Debug("Ignoring workspace module outside the project directory", "module", member)
+				continue
+			}
+			modules = append(modules, member)
+		}
+		slices.Sort(modules)
+		slog.Info("Found modules in Go workspace", "modules", len(modules))
+		return modules, nil
+	}
+
+	var modules []string
+	err = walkSourceDirs(rootDir, func(dir string, entries []fs.DirEntry) {
+		if slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return !e.IsDir() && e.Name() == "go.mod" }) {
+			modules = append(modules, dir)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding modules in %q: %w", rootDir, err)
+	}
+	slog.Info("Found modules in project directory", "modules", len(modules))
+	return modules, nil
+}
+
+// Return the absolute paths of the modules used by the Go workspace that applies to the specified directory,
+// or nil if the directory isn't part of a workspace.
+func workspaceModules(dir string) ([]string, error) {
+	goWork, err := runGo(dir, "env", "GOWORK")
+	if err != nil {
+		return nil, err
+	}
+	goWork = strings.TrimSpace(goWork)
+	if goWork == "" || goWork == "off" {
+		return nil, nil
+	}
+
+	out, err := runGo(filepath.Dir(goWork), "work", "edit", "-json", goWork)
+	if err != nil {
+		return nil, err
+	}
+	var work struct {
+		Use []struct {
+			DiskPath string
+		}
+	}
+	if err := json.Unmarshal([]byte(out), &work); err != nil {
+		return nil, fmt.Errorf("parsing workspace file %q: %w", goWork, err)
+	}
+
+	modules := make([]string, 0, len(work.Use))
+	for _, use := range work.Use {
+		path := use.DiskPath
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(goWork), path)
+		}
+		modules = append(modules, filepath.Clean(path))
+	}
+	return modules, nil
+}
+
+// Find every directory within the specified module that contains Go files, excluding nested modules.
+func findPackageDirs(moduleDir string) ([]string, error) {
+	var dirs []string
+	err := walkSourceDirs(moduleDir, func(dir string, entries []fs.DirEntry) {
+		if slices.ContainsFunc(entries, func(e fs.DirEntry) bool { return !e.IsDir() && strings.HasSuffix(e.Name(), ".go") }) {
+			dirs = append(dirs, dir)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("finding packages in %q: %w", moduleDir, err)
+	}
+
+	// Exclude directories belonging to nested modules, which are found by walking them separately
+	dirs = slices.DeleteFunc(dirs, func(dir string) bool {
+		for d := dir; isWithinDir(d, moduleDir) && d != moduleDir; d = filepath.Dir(d) {
+			if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+				return true
+			}
+		}
+		return false
+	})
+	return dirs, nil
+}
+
+// Walk the directory tree rooted at `rootDir` in lexical order, calling `visit` with the entries of every directory
+// that could contain Go source code. Like the `go` command, this skips `vendor` and `testdata` directories,
+// as well as directories whose names begin with "." or "_".
+func walkSourceDirs(rootDir string, visit func(dir string, entries []fs.DirEntry)) error {
+	return filepath.WalkDir(rootDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != rootDir {
+			name := d.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		visit(path, entries)
+		return nil
+	})
+}
+
+// Return whether `path` is the same as `dir` or inside it.
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Run a `go` command in the specified directory, returning its standard output.
+func runGo(dir string, args ...string) (string, error) {
+	cmd := exec.Command("go", args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("running go %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
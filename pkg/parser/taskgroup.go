@@ -0,0 +1,171 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Implementation of the Task interface that runs several tasks at once, so packages only have to be loaded
+// a single time. Every method is forwarded to each of the underlying tasks in order.
+type taskGroup struct {
+	tasks []Task
+}
+
+// Compile-time interface implementation checks
+var (
+	_ Task              = (*taskGroup)(nil)
+	_ PartialTask       = (*taskGroup)(nil)
+	_ GeneratedFileTask = (*taskGroup)(nil)
+	_ SyntaxOnlyTask    = (*taskGroup)(nil)
+	_ SummaryTask       = (*taskGroup)(nil)
+	_ ReleasingTask     = (*taskGroup)(nil)
+)
+
+// Return the names of all the tasks in the group, joined like "statistics+analyze"
+func (g *taskGroup) Name() string {
+	names := make([]string, len(g.tasks))
+	for i, t := range g.tasks {
+		names[i] = t.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+func (g *taskGroup) Visit(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	for _, t := range g.tasks {
+		t.Visit(file, fset, pkg)
+	}
+}
+
+// Forward the generated file to every task that implements GeneratedFileTask
+func (g *taskGroup) VisitGenerated(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	for _, t := range g.tasks {
+		if gt, ok := t.(GeneratedFileTask); ok {
+			gt.VisitGenerated(file, fset, pkg)
+		}
+	}
+}
+
+// Forward the syntax-only file to every task that implements SyntaxOnlyTask
+func (g *taskGroup) VisitSyntaxOnly(file *ast.File, fset *token.FileSet, pkg *packages.Package) {
+	for _, t := range g.tasks {
+		if st, ok := t.(SyntaxOnlyTask); ok {
+			st.VisitSyntaxOnly(file, fset, pkg)
+		}
+	}
+}
+
+// Forward the notification to every task that implements ReleasingTask
+func (g *taskGroup) ReleasePackages() {
+	for _, t := range g.tasks {
+		releasePackages(t)
+	}
+}
+
+func (g *taskGroup) Clone() Task {
+	clones := make([]Task, len(g.tasks))
+	for i, t := range g.tasks {
+		clones[i] = t.Clone()
+	}
+	return &taskGroup{tasks: clones}
+}
+
+func (g *taskGroup) Merge(other Task) error {
+	o, ok := other.(*taskGroup)
+	if !ok || len(o.tasks) != len(g.tasks) {
+		return fmt.Errorf("cannot merge %T into task group %q", other, g.Name())
+	}
+	for i, t := range g.tasks {
+		if err := t.Merge(o.tasks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *taskGroup) SetProjectDir(dir string) {
+	for _, t := range g.tasks {
+		t.SetProjectDir(dir)
+	}
+}
+
+// Report the results of every task, even if some of them fail
+func (g *taskGroup) ReportResults() error {
+	var errs []error
+	for _, t := range g.tasks {
+		if err := t.ReportResults(); err != nil {
+			errs = append(errs, fmt.Errorf("reporting %s task results: %w", t.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Report the summary of every task that implements SummaryTask, even if some of them fail
+func (g *taskGroup) ReportSummary(units int) error {
+	var errs []error
+	for _, t := range g.tasks {
+		if st, ok := t.(SummaryTask); ok {
+			if err := st.ReportSummary(units); err != nil {
+				errs = append(errs, fmt.Errorf("reporting %s task summary: %w", t.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Mark the results of every task that implements PartialTask as incomplete
+func (g *taskGroup) MarkPartial() {
+	for _, t := range g.tasks {
+		markPartial(t)
+	}
+}
+
+// Combine the cache keys of every task. Only used when every task implements CacheableTask.
+func (g *taskGroup) CacheKey() string {
+	keys := make([]string, len(g.tasks))
+	for i, t := range g.tasks {
+		keys[i] = t.(CacheableTask).CacheKey()
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// Encode the results of every task as a JSON array. Only used when every task implements CacheableTask.
+func (g *taskGroup) EncodeResults() ([]byte, error) {
+	results := make([]json.RawMessage, len(g.tasks))
+	for i, t := range g.tasks {
+		data, err := t.(CacheableTask).EncodeResults()
+		if err != nil {
+			return nil, fmt.Errorf("encoding %s task results: %w", t.Name(), err)
+		}
+		results[i] = data
+	}
+	return json.Marshal(results)
+}
+
+// Decode results produced by EncodeResults into every task. Only used when every task implements CacheableTask.
+func (g *taskGroup) DecodeResults(data []byte) error {
+	var results []json.RawMessage
+	if err := json.Unmarshal(data, &results); err != nil {
+		return err
+	}
+	if len(results) != len(g.tasks) {
+		return fmt.Errorf("expected results for %d tasks, but found %d", len(g.tasks), len(results))
+	}
+	for i, t := range g.tasks {
+		if err := t.(CacheableTask).DecodeResults(results[i]); err != nil {
+			return fmt.Errorf("decoding %s task results: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+func (g *taskGroup) Close() {
+	for _, t := range g.tasks {
+		t.Close()
+	}
+}
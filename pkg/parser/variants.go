@@ -0,0 +1,112 @@
+package parser
+
+// Handles the different "variants" of each package that are loaded when test files are included.
+
+import (
+	"cmp"
+	"go/ast"
+	"go/token"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Represents a loaded package along with the subset of its files that should actually be visited.
+// When loading with `packages.Config.Tests`, the same source file can belong to multiple variants of a package
+// (e.g. both `pkg` and `pkg [pkg.test]`), so each file is only assigned to one of them.
+type packageVariant struct {
+	pkg   *packages.Package
+	files []*ast.File
+}
+
+// Selects a single package variant for every source file in the loaded packages, so each file is visited exactly once.
+// Loading packages with `Tests: true` returns several variants of each package that has tests:
+//   - `pkg`, containing only the non-test files
+//   - `pkg [pkg.test]`, containing the non-test files and the internal test files
+//   - `pkg_test [pkg.test]`, containing the external test files
+//   - `pkg.test`, the generated test executable, which is always ignored
+//
+// Files are assigned to the variant with the most complete type information, which is the one with the most files
+// (i.e. the test variant, whose type information also covers the test files), then the one with the fewest errors.
+// The returned variants are in the same order as the provided packages, and variants without files are omitted.
+func selectPackageVariants(pkgs []*packages.Package, fset *token.FileSet) []packageVariant {
+	// Rank the candidate variants from most to least complete, ignoring generated test executables
+	candidates := make([]*packages.Package, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		if isTestMain(pkg) {
+			slog.Debug("Ignoring generated test executable package", "package", pkg.ID)
+			continue
+		}
+		candidates = append(candidates, pkg)
+	}
+	slices.SortStableFunc(candidates, func(a, b *packages.Package) int {
+		return cmp.Or(
+			cmp.Compare(len(b.Syntax), len(a.Syntax)),
+			cmp.Compare(len(a.Errors), len(b.Errors)),
+			strings.Compare(a.ID, b.ID),
+		)
+	})
+
+	// Assign each file to the first (i.e. most complete) variant that contains it
+	selected := make(map[*packages.Package][]*ast.File, len(candidates))
+	assigned := make(map[string]string) // maps file paths to the ID of the variant they were assigned to
+	for _, pkg := range candidates {
+		for _, file := range pkg.Syntax {
+			filePath := fset.Position(file.FileStart).Filename
+			if chosen, found := assigned[filePath]; found {
+				slog.Debug("Skipping file already assigned to another package variant", "file", filePath, "variant", pkg.ID, "chosenVariant", chosen)
+				continue
+			}
+			assigned[filePath] = pkg.ID
+			selected[pkg] = append(selected[pkg], file)
+		}
+	}
+
+	// Restore the original package order for the selected variants
+	variants := make([]packageVariant, 0, len(selected))
+	for _, pkg := range pkgs {
+		files, ok := selected[pkg]
+		if !ok {
+			continue
+		}
+		slog.Debug("Selected package variant", "variant", pkg.ID, "files", len(files), "totalFiles", len(pkg.Syntax))
+		variants = append(variants, packageVariant{pkg: pkg, files: files})
+	}
+
+	slog.Info("Selected package variants to visit", "loadedPackages", len(pkgs), "selectedVariants", len(variants), "files", len(assigned))
+	return variants
+}
+
+// Returns whether the package is a test executable generated by `go test` (e.g. `pkg.test`), whose files
+// are located in the build cache rather than the project itself.
+func isTestMain(pkg *packages.Package) bool {
+	return pkg.Name == "main" && !strings.Contains(pkg.ID, " ") && strings.HasSuffix(pkg.ID, ".test")
+}
+
+// Remove the files that were already selected from another build configuration from every package variant,
+// dropping variants that have no files left, and add the remaining files to `selected`.
+// This ensures that each file is only visited once, using the first build configuration that includes it.
+func excludeSelectedFiles(variants []packageVariant, fset *token.FileSet, selected map[string]bool, config BuildConfig) []packageVariant {
+	remaining := make([]packageVariant, 0, len(variants))
+	for _, variant := range variants {
+		files := make([]*ast.File, 0, len(variant.files))
+		for _, file := range variant.files {
+			filePath := fset.Position(file.FileStart).Filename
+			if selected[filePath] {
+				continue
+			}
+			selected[filePath] = true
+			files = append(files, file)
+		}
+		if len(files) == 0 {
+			continue
+		}
+		variant.files = files
+		remaining = append(remaining, variant)
+	}
+
+	slog.Debug("Selected files for build configuration", "config", config, "variants", len(remaining))
+	return remaining
+}
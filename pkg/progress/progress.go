@@ -0,0 +1,469 @@
+// Tracks the progress of the parser and reports it to the user, either as a live status display on a terminal
+// or as periodic log messages.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Represents the way progress is reported to the user.
+type Mode int
+
+const (
+	ModeAuto Mode = iota // Use ModeLive if the output is a terminal, otherwise ModeLog
+	ModeLive             // Continuously redraw a status display at the bottom of the terminal
+	ModeLog              // Periodically log the progress as structured log messages
+	ModeNone             // Don't report progress
+)
+
+// Return the Mode corresponding to the given string.
+func ModeFromString(mode string) Mode {
+	switch strings.ToLower(mode) {
+	case "auto", "":
+		return ModeAuto
+	case "live":
+		return ModeLive
+	case "log":
+		return ModeLog
+	case "none":
+		return ModeNone
+	default:
+		slog.Warn("Unknown progress mode", "mode", mode)
+		return ModeAuto
+	}
+}
+
+// Return the string representation of the Mode
+func (m Mode) String() string {
+	switch m {
+	case ModeLive:
+		return "live"
+	case ModeLog:
+		return "log"
+	case ModeNone:
+		return "none"
+	default:
+		return "auto"
+	}
+}
+
+const (
+	liveInterval = 200 * time.Millisecond // how often the live status display is redrawn
+	logInterval  = 10 * time.Second       // how often progress is logged in ModeLog
+
+	// Maximum width of each line in the live status display. Longer lines are truncated, since lines that wrap
+	// would prevent the display from being cleared properly.
+	maxLineWidth = 80
+)
+
+// Collects progress counters for every unit of the project being parsed, and periodically reports them.
+// All methods are safe for concurrent use, and calling them on a nil Tracker does nothing.
+type Tracker struct {
+	mode Mode
+	out  io.Writer // where the live status display is drawn
+
+	mu         sync.Mutex
+	units      []*Unit // every unit started since the last call to Start, in order
+	rootDir    string  // the project directory, which unit directories are displayed relative to
+	totalUnits int     // the number of units expected to be parsed, or 0 if unknown
+	startTime  time.Time
+	stop       chan struct{} // closed to stop the reporting goroutine
+	done       chan struct{} // closed once the reporting goroutine has stopped
+	drawnLines int           // the number of lines in the live status display that is currently on screen
+	suspended  int           // the number of active calls to Suspend, which prevent the live display from being drawn
+}
+
+// Create a new Tracker that reports progress using the specified mode. The live status display (if used) is drawn to `out`.
+// ModeAuto must be resolved by the caller, because only the caller knows whether its output is a terminal.
+func NewTracker(mode Mode, out io.Writer) *Tracker {
+	if mode == ModeAuto {
+		mode = ModeLog
+	}
+	return &Tracker{mode: mode, out: out}
+}
+
+// Progress counters for a single unit of the project (i.e. a directory that is loaded and parsed on its own).
+// Calling methods on a nil Unit does nothing.
+type Unit struct {
+	dir string
+
+	packagesTotal atomic.Int64
+	packagesDone  atomic.Int64
+	filesTotal    atomic.Int64
+	filesDone     atomic.Int64
+	tests         atomic.Int64
+	refactors     atomic.Int64
+	loaded        atomic.Bool // whether the unit's packages have been loaded, meaning its totals are known
+	finished      atomic.Bool
+}
+
+// Start reporting progress for a new run of the parser on the project in `rootDir`, which is expected to be
+// split into `totalUnits` units. Resets the counters of every unit from previous runs.
+func (t *Tracker) Start(rootDir string, totalUnits int) {
+	if t == nil || t.mode == ModeNone {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		return // Already running
+	}
+	t.units = nil
+	t.rootDir = rootDir
+	t.totalUnits = totalUnits
+	t.startTime = time.Now()
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+	go t.run(t.stop, t.done)
+}
+
+// Stop reporting progress, removing the live status display or logging the final progress.
+func (t *Tracker) Stop() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	stop, done := t.stop, t.done
+	t.stop, t.done = nil, nil
+	t.mu.Unlock()
+	if stop == nil {
+		return // Not running
+	}
+
+	close(stop)
+	<-done
+
+	switch t.mode {
+	case ModeLive:
+		t.mu.Lock()
+		t.clearLocked()
+		t.mu.Unlock()
+	case ModeLog:
+		t.logProgress()
+	}
+}
+
+// Periodically report progress until `stop` is closed.
+func (t *Tracker) run(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	interval := logInterval
+	if t.mode == ModeLive {
+		interval = liveInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			switch t.mode {
+			case ModeLive:
+				t.mu.Lock()
+				t.drawLocked()
+				t.mu.Unlock()
+			case ModeLog:
+				t.logProgress()
+			}
+		}
+	}
+}
+
+// Begin tracking a new unit of the project, returning its progress counters.
+func (t *Tracker) StartUnit(dir string) *Unit {
+	if t == nil || t.mode == ModeNone {
+		return nil
+	}
+	unit := &Unit{dir: dir}
+	t.mu.Lock()
+	t.units = append(t.units, unit)
+	t.mu.Unlock()
+	return unit
+}
+
+// Return the counters of the most recently started unit in the specified directory,
+// or nil if no unit has been started there.
+func (t *Tracker) Unit(dir string) *Unit {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, unit := range slices.Backward(t.units) {
+		if unit.dir == dir {
+			return unit
+		}
+	}
+	return nil
+}
+
+// Record that the unit's packages were loaded, containing the specified number of packages and files to visit.
+func (u *Unit) Loaded(packages, files int) {
+	if u == nil {
+		return
+	}
+	u.packagesTotal.Store(int64(packages))
+	u.filesTotal.Store(int64(files))
+	u.loaded.Store(true)
+}
+
+// Record that a package was finished, either by visiting it or by restoring its results from the cache.
+func (u *Unit) PackageDone() {
+	if u != nil {
+		u.packagesDone.Add(1)
+	}
+}
+
+// Record that the specified number of files were visited or skipped.
+func (u *Unit) FilesDone(n int) {
+	if u != nil {
+		u.filesDone.Add(int64(n))
+	}
+}
+
+// Record that the specified number of test cases were analyzed.
+func (u *Unit) TestsAnalyzed(n int) {
+	if u != nil {
+		u.tests.Add(int64(n))
+	}
+}
+
+// Record that a refactored test case was executed.
+func (u *Unit) RefactorExecuted() {
+	if u != nil {
+		u.refactors.Add(1)
+	}
+}
+
+// Record that the unit was completely parsed.
+func (u *Unit) Finish() {
+	if u != nil {
+		u.finished.Store(true)
+	}
+}
+
+// Return the fraction of the unit's files that have been processed, between 0 and 1.
+func (u *Unit) fraction() float64 {
+	if u.finished.Load() {
+		return 1
+	}
+	total := u.filesTotal.Load()
+	if !u.loaded.Load() || total == 0 {
+		return 0
+	}
+	return min(float64(u.filesDone.Load())/float64(total), 1)
+}
+
+// Hide the live status display while `ReportResults` or similar functions print to the terminal, returning
+// a function that allows it to be drawn again. Log messages don't need this, since Writer handles them.
+func (t *Tracker) Suspend() (resume func()) {
+	if t == nil || t.mode != ModeLive {
+		return func() {}
+	}
+	t.mu.Lock()
+	t.suspended++
+	t.clearLocked()
+	t.mu.Unlock()
+
+	return sync.OnceFunc(func() {
+		t.mu.Lock()
+		t.suspended--
+		t.mu.Unlock()
+	})
+}
+
+// Wrap a writer that shares the terminal with the live status display (usually the log output), so that the
+// display is cleared before anything is written and redrawn afterwards. Returns `w` unchanged if the live display isn't used.
+func (t *Tracker) Writer(w io.Writer) io.Writer {
+	if t == nil || t.mode != ModeLive {
+		return w
+	}
+	return &trackerWriter{tracker: t, w: w}
+}
+
+type trackerWriter struct {
+	tracker *Tracker
+	w       io.Writer
+}
+
+func (tw *trackerWriter) Write(p []byte) (int, error) {
+	t := tw.tracker
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.clearLocked()
+	n, err := tw.w.Write(p)
+	t.drawLocked()
+	return n, err
+}
+
+// Draw the live status display in place of the previous one. Must be called while holding `mu`.
+func (t *Tracker) drawLocked() {
+	if t.stop == nil || t.suspended > 0 {
+		return
+	}
+	lines := t.statusLines()
+	t.clearLocked()
+	fmt.Fprint(t.out, strings.Join(lines, "\n"))
+	t.drawnLines = len(lines)
+}
+
+// Erase the live status display from the terminal. Must be called while holding `mu`.
+func (t *Tracker) clearLocked() {
+	if t.drawnLines == 0 {
+		return
+	}
+	// Move to the first line of the display, then clear everything after the cursor
+	var b strings.Builder
+	b.WriteString("\r")
+	if t.drawnLines > 1 {
+		fmt.Fprintf(&b, "\x1b[%dA", t.drawnLines-1)
+	}
+	b.WriteString("\x1b[J")
+	fmt.Fprint(t.out, b.String())
+	t.drawnLines = 0
+}
+
+// Summary of the progress of every unit, used to build the status display and log messages.
+type snapshot struct {
+	unitsDone, totalUnits       int
+	packagesDone, packagesTotal int64
+	filesDone, filesTotal       int64
+	tests, refactors            int64
+	elapsed, eta                time.Duration // `eta` is negative if it can't be estimated yet
+	active                      []*Unit       // units that have started but not finished
+	fraction                    float64
+}
+
+// Collect the current progress of every unit. Must be called while holding `mu`.
+func (t *Tracker) snapshotLocked() snapshot {
+	s := snapshot{totalUnits: max(t.totalUnits, len(t.units)), elapsed: time.Since(t.startTime), eta: -1}
+
+	var fractionSum float64
+	for _, unit := range t.units {
+		s.packagesDone += unit.packagesDone.Load()
+		s.packagesTotal += unit.packagesTotal.Load()
+		s.filesDone += unit.filesDone.Load()
+		s.filesTotal += unit.filesTotal.Load()
+		s.tests += unit.tests.Load()
+		s.refactors += unit.refactors.Load()
+		fractionSum += unit.fraction()
+		if unit.finished.Load() {
+			s.unitsDone++
+		} else {
+			s.active = append(s.active, unit)
+		}
+	}
+
+	if s.totalUnits > 0 {
+		s.fraction = fractionSum / float64(s.totalUnits)
+	}
+	// Estimate the remaining time assuming the rest of the project is parsed at the same rate so far
+	if s.fraction >= 1 {
+		s.eta = 0
+	} else if s.fraction > 0 {
+		s.eta = time.Duration(float64(s.elapsed) * (1 - s.fraction) / s.fraction).Round(time.Second)
+	}
+	return s
+}
+
+// Return the lines of the live status display: a summary of the entire run, then one line per active unit
+// if the project is split into multiple units.
+func (t *Tracker) statusLines() []string {
+	s := t.snapshotLocked()
+
+	if s.filesTotal == 0 && s.unitsDone == 0 {
+		return []string{fmt.Sprintf("Loading packages... (elapsed %s)", s.elapsed.Round(time.Second))}
+	}
+
+	// The most important information comes first, in case the line is truncated
+	summary := fmt.Sprintf("[%.0f%%, ETA %s] %d/%d packages, %d/%d files, %d tests", s.fraction*100, formatETA(s.eta),
+		s.packagesDone, s.packagesTotal, s.filesDone, s.filesTotal, s.tests)
+	if s.refactors > 0 {
+		summary += fmt.Sprintf(", %d refactors", s.refactors)
+	}
+	if s.totalUnits > 1 {
+		summary += fmt.Sprintf(" (%d/%d units)", s.unitsDone, s.totalUnits)
+	}
+
+	lines := []string{truncate(summary, maxLineWidth)}
+	if s.totalUnits > 1 {
+		for _, unit := range s.active {
+			lines = append(lines, truncate("  "+t.unitName(unit)+": "+unit.String(), maxLineWidth))
+		}
+	}
+	return lines
+}
+
+// Log the current progress, including the progress of each active unit if the project is split into multiple units.
+func (t *Tracker) logProgress() {
+	t.mu.Lock()
+	s := t.snapshotLocked()
+	t.mu.Unlock()
+
+	attrs := []any{
+		"packages", fmt.Sprintf("%d/%d", s.packagesDone, s.packagesTotal),
+		"files", fmt.Sprintf("%d/%d", s.filesDone, s.filesTotal),
+		"tests", s.tests,
+		"refactors", s.refactors,
+		"percent", fmt.Sprintf("%.0f%%", s.fraction*100),
+		"elapsed", s.elapsed.Round(time.Second),
+		"eta", formatETA(s.eta),
+	}
+	if s.totalUnits > 1 {
+		attrs = append([]any{"units", fmt.Sprintf("%d/%d", s.unitsDone, s.totalUnits)}, attrs...)
+	}
+	slog.Info("Progress", attrs...)
+
+	if s.totalUnits > 1 {
+		for _, unit := range s.active {
+			slog.Info("Unit progress", "unit", t.unitName(unit),
+				"packages", fmt.Sprintf("%d/%d", unit.packagesDone.Load(), unit.packagesTotal.Load()),
+				"files", fmt.Sprintf("%d/%d", unit.filesDone.Load(), unit.filesTotal.Load()),
+				"tests", unit.tests.Load())
+		}
+	}
+}
+
+// Return a single line summarizing the progress of the unit
+func (u *Unit) String() string {
+	if !u.loaded.Load() {
+		return "loading packages..."
+	}
+	return fmt.Sprintf("%d/%d packages, %d/%d files, %d tests",
+		u.packagesDone.Load(), u.packagesTotal.Load(), u.filesDone.Load(), u.filesTotal.Load(), u.tests.Load())
+}
+
+// Return the name of the unit to display, which is its directory relative to the project directory.
+func (t *Tracker) unitName(u *Unit) string {
+	if rel, err := filepath.Rel(t.rootDir, u.dir); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return u.dir
+}
+
+// Shorten the string to at most `width` characters, replacing the end with "..." if it's too long.
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width-3]) + "..."
+}
+
+// Format the estimated remaining time, which is negative if it's unknown.
+func formatETA(eta time.Duration) string {
+	if eta < 0 {
+		return "unknown"
+	}
+	return eta.String()
+}
@@ -19,6 +19,7 @@ type AnalysisResult struct {
 	ScenarioSet      *ScenarioSet         // the set of scenarios defined in this test case, if it is table-driven
 	ParsedStatements []*ExpandedStatement // the list of parsed and fully-expanded statements in the test case
 	ImportedPackages []string             // the list of imported packages in the test case's file
+	TypeInfoComplete bool                 // whether the test's package was loaded without errors, meaning its type information is complete
 
 	// Refactoring result - only available after running `AttemptRefactoring()`
 	RefactorResult RefactorResult // the result of refactoring the test case
@@ -37,6 +38,15 @@ func Analyze(tc *TestCase) *AnalysisResult {
 		slog.Error("Cannot analyze TestCase because it has nil syntax data", "testCase", tc)
 		return nil
 	}
+
+	// Errors in any file of the package can leave type information missing, in which case the analysis falls back
+	// to syntax-only heuristics where possible
+	if pkg := tc.GetPackageInfo(); pkg != nil {
+		result.TypeInfoComplete = len(pkg.Errors) == 0 && !pkg.IllTyped
+	}
+	if !result.TypeInfoComplete {
+		slog.Debug("Analyzing TestCase with incomplete type information", "testCase", tc)
+	}
 	fset := tc.FileSet()
 	if fset == nil {
 		slog.Error("Cannot analyze TestCase because FileSet is nil", "testCase", tc)
@@ -87,10 +97,18 @@ func (ar *AnalysisResult) GetCSVHeaders() []string {
 		"project",
 		"filePath",
 		"package",
+		"packageVariant",
 		"name",
+		"kind",
+		"suite",
+		"suiteRunner",
+		"buildConstraint",
+		"typeInfoComplete",
 		"isTableDriven",
 		"scenarioDataStructure",
 		"scenarioCount",
+		"scenarioConstruction",
+		"scenarioScope",
 		"scenarioNameField",
 		"scenarioExpectedFields",
 		"scenarioHasFunctionFields",
@@ -120,10 +138,18 @@ func (ar *AnalysisResult) EncodeAsCSV() []string {
 		tc.ProjectName,
 		tc.FilePath,
 		tc.PackageName,
+		tc.PackageVariant,
 		tc.TestName,
+		tc.Kind.String(),
+		tc.Suite,
+		tc.SuiteRunner,
+		tc.BuildConstraint,
+		strconv.FormatBool(ar.TypeInfoComplete),
 		strconv.FormatBool(ss.IsTableDriven()),
 		ss.DataStructure.String(),
 		strconv.Itoa(len(ss.Scenarios)),
+		ss.Construction.String(),
+		ss.Scope.String(),
 		ss.NameField,
 		strings.Join(ss.ExpectedFields, ", "),
 		strconv.FormatBool(ss.HasFunctionFields),
@@ -8,6 +8,7 @@ import (
 	"slices"
 
 	"github.com/maxgreen01/go-test-parser/pkg/asttools"
+	"golang.org/x/tools/go/packages"
 )
 
 // Attempts to extract the table-driven properties of a test case using information extracted from its parsed statements
@@ -22,9 +23,17 @@ func IdentifyScenarioSet(tc *TestCase, statements []*ExpandedStatement) *Scenari
 		return nil
 	}
 
+	// Ginkgo tables define their scenarios using `Entry` calls instead of a loop
+	if tc.spec != nil && tc.spec.Type == GinkgoTableNode {
+		return identifyGinkgoTable(tc, tc.spec)
+	}
+
 	// Initialize the TestCase's ScenarioSet, whose fields will be populated throughout this method with relevant data
 	ss := &ScenarioSet{TestCase: tc}
 
+	// The expanded form of a helper function called by the runner to get the scenarios, like `range testCases()`
+	var tableSource *ExpandedStatement
+
 	// Iterate test statements in reverse to find the runner loop before trying to find the scenarios
 	stmtsReversed := slices.Clone(stmts)
 	slices.Reverse(stmtsReversed)
@@ -44,7 +53,7 @@ outerStmtLoop:
 				slog.Debug("Found range statement in test case", "testCase", tc.TestName)
 
 				// Make sure the loop ranges over a valid data structure, and save it if so
-				ss.detectScenarioDataStructure(tc.TypeOf(rangeStmt.X))
+				ss.detectScenarioDataStructure(tc.typeOfWithFallback(rangeStmt.X))
 
 				if ss.DataStructure == ScenarioNoDS {
 					// Can't do anything if the loop data structure is unknown
@@ -60,57 +69,63 @@ outerStmtLoop:
 					}
 				}
 
+				// Check if the scenarios are built by a helper function called in the range statement, like `range testCases()`
+				if call, ok := rangeStmt.X.(*ast.CallExpr); ok {
+					tableSource = ExpandStatement(&ast.ExprStmt{X: call}, tc, true)
+					for stmt := range tableSource.All() {
+						if ss.identifyScenariosInStmt(stmt) {
+							slog.Debug("Found scenario definition in helper function called by the range statement", "testCase", tc, "scenarios", len(ss.Scenarios))
+							break
+						}
+					}
+				}
+
 				ss.Runner = rangeStmt
 
 				continue outerStmtLoop // Move to the next statement
-			}
+			} else if forStmt, ok := stmt.(*ast.ForStmt); ok {
+				slog.Debug("Found for loop statement in test case", "testCase", tc.TestName)
+
+				// Only loops like `for i := 0; i < len(tests); i++` can iterate over scenarios
+				_, table := indexLoopTable(forStmt)
+				if table == nil {
+					slog.Debug("Detected a for loop in test case, but it doesn't index a data structure", "testCase", tc)
+					continue outerStmtLoop // Try checking for additional loops
+				}
+
+				// Make sure the loop indexes a valid data structure, and save it if so. Maps can't be iterated using an index.
+				if ds, _ := ss.detectScenarioDataStructure(tc.typeOfWithFallback(table)); ds != ScenarioStructListDS {
+					slog.Debug("Detected a for loop in test case, but the data structure is unknown", "testCase", tc)
+					ss.DataStructure, ss.ScenarioType, ss.NameField = ScenarioNoDS, nil, ""
+					continue outerStmtLoop // Try checking for additional loops
+				}
 
-			// todo LATER add support for `for-i` loops
-			//  else if forStmt, ok := stmt.(*ast.ForStmt); ok {
-			// 	slog.Debug("Found loop statement in test case", "test", t.Name)
-			// 	t.TableDrivenType += ", with for loop"
-			// 	detectTRun(forStmt.Body)
-			// }
+				ss.Runner = forStmt
+
+				continue outerStmtLoop // Move to the next statement
+			}
 		}
 
 		// Iterate over each component of the expanded statement, i.e. look into expanded helper functions
 		for stmt := range expanded.All() {
 
-			// Search for variable assignments matching the detected scenario data structure, with the goal of finding the scenario definitions
-			if ss.Scenarios == nil && ss.ScenarioType != nil {
-				switch assignment := stmt.(type) {
-				case *ast.AssignStmt:
-					// Statements like `scenarios := []Scenario{...}`
-					for _, expr := range assignment.Rhs {
-						found := ss.IdentifyScenarios(expr, tc)
-						if found {
-							slog.Debug("Found scenario definition in function body", "testCase", tc, "scenarios", len(ss.Scenarios))
-							continue outerStmtLoop // Move to the next statement
-						}
-					}
-				case *ast.DeclStmt:
-					// Statements like `var scenarios = []Scenario{...}`
-					// todo CLEANUP mostly the same code as checking in file decls below
-					if genDecl, ok := assignment.Decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
-						// Loop over the right-hand side expressions of each variable declaration
-						for _, spec := range genDecl.Specs {
-							if valueSpec, ok := spec.(*ast.ValueSpec); ok {
-								for _, expr := range valueSpec.Values {
-									found := ss.IdentifyScenarios(expr, tc)
-									if found {
-										slog.Debug("Found scenario definition in function body", "testCase", tc, "scenarios", len(ss.Scenarios))
-										continue outerStmtLoop // Move to the next statement
-									}
-								}
-							}
-						}
-					}
-				}
+			// Search for variable assignments matching the detected scenario data structure, with the goal of finding the scenario definitions.
+			// Helper functions that build the scenarios are expanded too, so their return values are checked as well.
+			if ss.Scenarios == nil && ss.ScenarioType != nil && ss.identifyScenariosInStmt(stmt) {
+				slog.Debug("Found scenario definition in function body", "testCase", tc, "scenarios", len(ss.Scenarios))
+				continue outerStmtLoop // Move to the next statement
 			}
 		} // end of loop over expanded statement components
 	} // end of loop over expanded statements
 
-	// If the loop was found but the Scenario definitions were not, check the file declarations in case they were defined outside the function
+	// If the loop was found but the Scenario definitions were not, find the declaration of the package-level variable it uses,
+	// which may be in any file of the package
+	if ss.Scenarios == nil && ss.ScenarioType != nil && ss.identifyDeclaredScenarios(ss.runnerTable()) {
+		slog.Debug("Found scenario definition in package-level variable", "testCase", tc, "scope", ss.Scope, "scenarios", len(ss.Scenarios))
+	}
+
+	// If the variable can't be resolved (e.g. because type information is missing or incomplete) or its initializer isn't
+	// a literal, check the file declarations for a variable of the same type instead
 	if ss.Scenarios == nil && ss.ScenarioType != nil {
 		slog.Debug("No scenarios found in the test case, checking file declarations", "testCase", tc)
 
@@ -129,6 +144,7 @@ outerStmtLoop:
 							for _, expr := range valueSpec.Values {
 								found := ss.IdentifyScenarios(expr, tc)
 								if found {
+									ss.Scope = ScenarioScopeFile
 									slog.Debug("Found scenario definition in file declarations", "testCase", tc, "scenarios", len(ss.Scenarios))
 									break declLoop // Stop checking file declarations
 								}
@@ -140,11 +156,359 @@ outerStmtLoop:
 		}
 	}
 
+	// Scenarios may also be added one at a time using `append`, possibly after being initialized with a literal
+	if ss.DataStructure == ScenarioStructListDS && ss.identifyAppendedScenarios(append(slices.Clone(stmts), tableSource)) {
+		slog.Debug("Found scenarios added using append", "testCase", tc, "scenarios", len(ss.Scenarios))
+	}
+
+	// Scenarios found anywhere else were defined inside the test or a helper function
+	if len(ss.Scenarios) > 0 && ss.Scope == ScenarioScopeNone {
+		ss.Scope = ScenarioScopeLocal
+	}
+
 	// Attempt to perform additional analysis on the ScenarioSet
 	ss.Analyze()
 	return ss
 }
 
+// Checks whether the statement defines scenarios matching the detected scenario data structure, and if so, saves them.
+// Recognizes assignments like `scenarios := []Scenario{...}`, declarations like `var scenarios = []Scenario{...}`,
+// and return statements like `return []Scenario{...}` inside helper functions. Returns whether the scenarios were saved.
+func (ss *ScenarioSet) identifyScenariosInStmt(stmt ast.Stmt) bool {
+	tc := ss.TestCase
+	var exprs []ast.Expr
+	switch x := stmt.(type) {
+	case *ast.ReturnStmt:
+		exprs = x.Results
+	case *ast.AssignStmt:
+		exprs = x.Rhs
+	case *ast.DeclStmt:
+		// todo CLEANUP mostly the same code as checking in file decls in IdentifyScenarioSet
+		if genDecl, ok := x.Decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
+			// Loop over the right-hand side expressions of each variable declaration
+			for _, spec := range genDecl.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					exprs = append(exprs, valueSpec.Values...)
+				}
+			}
+		}
+	}
+	for _, expr := range exprs {
+		if ss.IdentifyScenarios(expr, tc) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the expression for the data structure used by the runner loop, like `tests` in `for _, tt := range tests`
+// or `for i := 0; i < len(tests); i++`. Returns nil if the runner wasn't found.
+func (ss *ScenarioSet) runnerTable() ast.Expr {
+	switch runner := ss.Runner.(type) {
+	case *ast.RangeStmt:
+		return runner.X
+	case *ast.ForStmt:
+		_, table := indexLoopTable(runner)
+		return table
+	}
+	return nil
+}
+
+// Finds the scenarios defined by the initializer of the package-level variable referenced by the expression, which
+// may be declared in any file of the test's package. Variables of the package under test are also found when the test
+// is in an external `_test` package, like `for _, tt := range pkg.Tests`. Returns whether the scenarios were saved.
+func (ss *ScenarioSet) identifyDeclaredScenarios(table ast.Expr) bool {
+	tc := ss.TestCase
+	variable, ok := tc.referencedObject(table).(*types.Var)
+	if !ok || variable.Pkg() == nil || variable.Parent() != variable.Pkg().Scope() {
+		return false // Local variables are found by searching the test's statements instead
+	}
+	pkg := tc.declaringPackage(variable.Pkg())
+	if pkg == nil {
+		slog.Debug("Cannot find the package that declares the scenario variable", "testCase", tc, "variable", variable.Name(), "package", variable.Pkg().Path())
+		return false
+	}
+	value, file := findVarInitializer(variable, pkg.Syntax)
+	if value == nil {
+		return false
+	}
+
+	// The initializer must be analyzed using the type information of the package that declares it
+	declTC := tc
+	if pkg != tc.pkgInfo {
+		copied := *tc
+		copied.pkgInfo = pkg
+		declTC = &copied
+	}
+	ss.TestCase = declTC
+	found := ss.IdentifyScenarios(value, declTC)
+	ss.TestCase = tc
+	if !found {
+		return false
+	}
+
+	if file == tc.GetFile() {
+		ss.Scope = ScenarioScopeFile
+	} else {
+		ss.Scope = ScenarioScopePackage
+	}
+	return true
+}
+
+// Returns the loaded package whose types are `typesPkg`, which is either the test's own package or one of the packages
+// it imports directly (such as the package under test, when the test is in an external `_test` package).
+// Returns nil if the package isn't found.
+func (tc *TestCase) declaringPackage(typesPkg *types.Package) *packages.Package {
+	if tc.pkgInfo == nil {
+		return nil
+	}
+	if tc.pkgInfo.Types == typesPkg {
+		return tc.pkgInfo
+	}
+	for _, imported := range tc.pkgInfo.Imports {
+		if imported.Types == typesPkg {
+			return imported
+		}
+	}
+	return nil
+}
+
+// Returns the expression used to initialize the package-level variable, and the file containing its declaration.
+// Returns nil if the declaration isn't found or doesn't initialize each variable separately, like `var a, b = f()`.
+func findVarInitializer(variable *types.Var, files []*ast.File) (ast.Expr, *ast.File) {
+	for _, file := range files {
+		if file.Pos() > variable.Pos() || variable.Pos() >= file.End() {
+			continue
+		}
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if !ok || len(valueSpec.Values) != len(valueSpec.Names) {
+					continue
+				}
+				for i, name := range valueSpec.Names {
+					if name.Pos() == variable.Pos() {
+						return valueSpec.Values[i], file
+					}
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+// Finds scenarios added to the runner's table using `append` calls like `tests = append(tests, Scenario{...})`,
+// including calls inside loops and helper functions, and saves them after any scenarios that were already found.
+// Only calls that append to one of the table's variables (see tableVariables) are included, and calls inside the
+// runner loop are ignored. Returns whether any scenarios were found.
+func (ss *ScenarioSet) identifyAppendedScenarios(statements []*ExpandedStatement) bool {
+	tc := ss.TestCase
+	tableVars := ss.tableVariables(statements)
+	if len(tableVars) == 0 {
+		return false
+	}
+	seen := make(map[*ast.CallExpr]bool) // expanded statements can contain the same call more than once
+	var appended []ast.Expr
+	for _, expanded := range statements {
+		if expanded == nil || expanded.Stmt == ss.Runner {
+			continue
+		}
+		for stmt := range expanded.All() {
+			ast.Inspect(stmt, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || seen[call] || !isIdentNamed(call.Fun, "append") || call.Ellipsis.IsValid() || len(call.Args) < 2 {
+					return true
+				}
+				seen[call] = true
+				if !tableVars[tc.referencedObject(call.Args[0])] {
+					return true // Appending to another list, like the results of each scenario
+				}
+				for _, arg := range call.Args[1:] {
+					if typ := tc.typeOfWithFallback(arg); typ != nil && types.Identical(asttools.Unpointer(typ).Underlying(), ss.ScenarioType) {
+						appended = append(appended, arg)
+					}
+				}
+				return true
+			})
+		}
+	}
+	if len(appended) == 0 {
+		return false
+	}
+
+	for _, expr := range appended {
+		ss.Scenarios = append(ss.Scenarios, ss.newScenario(len(ss.Scenarios), expr))
+	}
+	// Scenarios built by a helper function are reported as such, even if some were added by the test itself
+	if construction := ss.constructionAt(appended[0].Pos(), true); construction > ss.Construction {
+		ss.Construction = construction
+	}
+	return true
+}
+
+// Returns the variables that store the runner's table: the variable used by the runner loop itself, and the variables
+// returned by a helper function that builds the table, like `buildTests` in `tests := buildTests()` or `testCases` in
+// `for _, tt := range testCases()`. Returns nil if the variables can't be resolved.
+func (ss *ScenarioSet) tableVariables(statements []*ExpandedStatement) map[types.Object]bool {
+	tc := ss.TestCase
+	vars := make(map[types.Object]bool)
+	var helperCalls []*ast.CallExpr
+	table := ast.Unparen(ss.runnerTable())
+	if call, ok := table.(*ast.CallExpr); ok {
+		helperCalls = append(helperCalls, call)
+	} else if obj := tc.referencedObject(table); obj != nil {
+		vars[obj] = true
+
+		// Find the helper functions whose results are assigned to the table variable
+		for _, expanded := range statements {
+			if expanded == nil {
+				continue
+			}
+			var lhs, rhs []ast.Expr
+			switch x := expanded.Stmt.(type) {
+			case *ast.AssignStmt:
+				lhs, rhs = x.Lhs, x.Rhs
+			case *ast.DeclStmt:
+				if genDecl, ok := x.Decl.(*ast.GenDecl); ok && genDecl.Tok == token.VAR {
+					for _, spec := range genDecl.Specs {
+						if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+							for _, name := range valueSpec.Names {
+								lhs = append(lhs, name)
+							}
+							rhs = append(rhs, valueSpec.Values...)
+						}
+					}
+				}
+			}
+			if len(lhs) != len(rhs) {
+				continue
+			}
+			for i, expr := range lhs {
+				if call, ok := ast.Unparen(rhs[i]).(*ast.CallExpr); ok && tc.referencedObject(expr) == obj {
+					helperCalls = append(helperCalls, call)
+				}
+			}
+		}
+	}
+
+	// Add the variables returned by each helper function, ignoring the returns of nested function literals
+	for _, call := range helperCalls {
+		definition, err := FindDefinition(call.Fun, tc, true)
+		if err != nil || definition == nil {
+			continue
+		}
+		funcDecl, ok := definition.Node.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil {
+			continue
+		}
+		ast.Inspect(funcDecl.Body, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.FuncLit:
+				return false
+			case *ast.ReturnStmt:
+				for _, result := range x.Results {
+					if obj := tc.referencedObject(result); obj != nil {
+						vars[obj] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return vars
+}
+
+// Returns the object referenced by an identifier or a selector expression like `pkg.Name`, or nil if the expression
+// has another form or its object can't be resolved.
+func (tc *TestCase) referencedObject(expr ast.Expr) types.Object {
+	switch x := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		return tc.ObjectOf(x)
+	case *ast.SelectorExpr:
+		return tc.ObjectOf(x.Sel)
+	}
+	return nil
+}
+
+// Returns how scenarios defined at the specified position were built, based on whether they're defined in the test
+// function itself, a package-level variable, or a helper function.
+func (ss *ScenarioSet) constructionAt(pos token.Pos, appended bool) ScenarioConstruction {
+	tc := ss.TestCase
+	inTest := tc.funcDecl != nil && tc.funcDecl.Pos() <= pos && pos < tc.funcDecl.End()
+	if funcDecl, _ := asttools.GetEnclosingFunction(pos, tc.GetPackageFiles()); !inTest && funcDecl != nil {
+		return ScenarioConstructionHelper
+	}
+	if appended {
+		return ScenarioConstructionAppend
+	}
+	return ScenarioConstructionLiteral
+}
+
+// Returns the name of the index variable and the data structure being indexed by a loop like
+// `for i := 0; i < len(tests); i++`. The data structure is taken from the `len` call in the loop condition,
+// or otherwise from the first index expression like `tests[i]` in the loop body.
+// Returns an empty name and a nil expression if the loop doesn't have this form.
+func indexLoopTable(loop *ast.ForStmt) (string, ast.Expr) {
+	// The loop must start at zero, like `i := 0`
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || len(init.Lhs) != 1 || len(init.Rhs) != 1 {
+		return "", nil
+	}
+	index, ok := init.Lhs[0].(*ast.Ident)
+	if lit, isLit := init.Rhs[0].(*ast.BasicLit); !ok || !isLit || lit.Kind != token.INT || lit.Value != "0" {
+		return "", nil
+	}
+
+	// The loop must increment the index by one, like `i++` or `i += 1`
+	switch post := loop.Post.(type) {
+	case *ast.IncDecStmt:
+		if post.Tok != token.INC || !isIdentNamed(post.X, index.Name) {
+			return "", nil
+		}
+	case *ast.AssignStmt:
+		if post.Tok != token.ADD_ASSIGN || len(post.Lhs) != 1 || len(post.Rhs) != 1 || !isIdentNamed(post.Lhs[0], index.Name) {
+			return "", nil
+		}
+		if lit, ok := post.Rhs[0].(*ast.BasicLit); !ok || lit.Value != "1" {
+			return "", nil
+		}
+	default:
+		return "", nil
+	}
+
+	// The loop condition must compare the index against a bound, like `i < len(tests)`
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok || cond.Op != token.LSS || !isIdentNamed(cond.X, index.Name) {
+		return "", nil
+	}
+	if call, ok := cond.Y.(*ast.CallExpr); ok && isIdentNamed(call.Fun, "len") && len(call.Args) == 1 {
+		return index.Name, call.Args[0]
+	}
+
+	// If the bound isn't the length of the data structure, look for the data structure being indexed instead
+	var table ast.Expr
+	ast.Inspect(loop.Body, func(n ast.Node) bool {
+		if indexExpr, ok := n.(*ast.IndexExpr); ok && table == nil && isIdentNamed(indexExpr.Index, index.Name) {
+			table = indexExpr.X
+		}
+		return table == nil
+	})
+	if table == nil {
+		return "", nil
+	}
+	return index.Name, table
+}
+
+// Returns whether the expression is an identifier with the specified name.
+func isIdentNamed(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
 // Detects the type of data structure used to store scenarios in a table-driven test as well as
 // the underlying type (usually a struct) used to define scenarios, then saves both to the `ScenarioSet`.
 // Also checks if the key of a map structure is used to define scenario names.
@@ -208,26 +572,39 @@ func (ss *ScenarioSet) IdentifyScenarios(expr ast.Expr, tc *TestCase) bool {
 		}
 
 		// Depending on the scenario data structure, extract and save the scenarios themselves
-		// todo LATER construct Scenario structs inside the cases.    also might have to make changes here to handle non-struct fields
 		switch ss.DataStructure {
 
 		case ScenarioStructListDS:
 			// Scenarios are directly stored as the elements of the slice
 			typ := tc.TypeOf(compositeLit.Elts[0])
-			if typ != nil && types.Identical(typ.Underlying(), ss.ScenarioType) {
-				ss.Scenarios = compositeLit.Elts
+			if !isValidType(typ) {
+				typ = syntaxElementType(tc.typeOfWithFallback(compositeLit))
+			}
+			if typ != nil && types.Identical(asttools.Unpointer(typ).Underlying(), ss.ScenarioType) {
+				for i, elt := range compositeLit.Elts {
+					ss.Scenarios = append(ss.Scenarios, ss.newScenario(i, elt))
+				}
+				ss.Construction = ss.constructionAt(compositeLit.Pos(), false)
 				return true
 			}
 
 		case ScenarioMapDS:
 			// Scenarios are stored as the values of the `KeyValueExpr` elements
 			kvExpr, ok := compositeLit.Elts[0].(*ast.KeyValueExpr)
-			if ok && types.Identical(tc.TypeOf(kvExpr.Value).Underlying(), ss.ScenarioType) {
+			if !ok {
+				return false
+			}
+			typ := tc.TypeOf(kvExpr.Value)
+			if !isValidType(typ) {
+				typ = syntaxElementType(tc.typeOfWithFallback(compositeLit))
+			}
+			if typ != nil && types.Identical(asttools.Unpointer(typ).Underlying(), ss.ScenarioType) {
 				for _, elt := range compositeLit.Elts {
 					if kvExpr, ok := elt.(*ast.KeyValueExpr); ok {
-						ss.Scenarios = append(ss.Scenarios, kvExpr)
+						ss.Scenarios = append(ss.Scenarios, ss.newScenario(len(ss.Scenarios), kvExpr))
 					}
 				}
+				ss.Construction = ss.constructionAt(compositeLit.Pos(), false)
 				return true
 			}
 		}
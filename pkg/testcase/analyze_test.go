@@ -0,0 +1,105 @@
+package testcase
+
+import (
+	"go/ast"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIdentifyScenarioSetForLoops(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "fori")
+
+	tests := []struct {
+		name        string
+		tableDriven bool
+		scenarios   int
+		nameField   string
+		usesSubtest bool
+	}{
+		{"TestForLen", true, 2, "name", false},
+		{"TestForPointer", true, 3, "name", false},
+		{"TestForBound", true, 2, "desc", false},
+		{"TestForSubtests", true, 2, "name", true},
+		{"TestForNotTable", false, 0, "", false},
+		{"TestForDecreasing", false, 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := analyzeFixtureFunc(t, funcs, tt.name)
+			if got := ar.IsTableDriven(); got != tt.tableDriven {
+				t.Fatalf("IsTableDriven() = %v, want %v", got, tt.tableDriven)
+			}
+			if !tt.tableDriven {
+				return
+			}
+			ss := ar.ScenarioSet
+			if _, ok := ss.Runner.(*ast.ForStmt); !ok {
+				t.Errorf("Runner is a %T, want *ast.ForStmt", ss.Runner)
+			}
+			if ss.DataStructure != ScenarioStructListDS || len(ss.Scenarios) != tt.scenarios {
+				t.Errorf("ScenarioSet has DataStructure = %s and %d scenarios, want structList and %d", ss.DataStructure, len(ss.Scenarios), tt.scenarios)
+			}
+			if ss.NameField != tt.nameField || ss.UsesSubtest != tt.usesSubtest {
+				t.Errorf("ScenarioSet has NameField = %q and UsesSubtest = %v, want %q and %v", ss.NameField, ss.UsesSubtest, tt.nameField, tt.usesSubtest)
+			}
+		})
+	}
+}
+
+func TestRefactorForLoopToSubtests(t *testing.T) {
+	ar := analyzeFixtureFunc(t, loadFixtureFuncs(t, "fori"), "TestForLen")
+	refactored, status, err := ar.refactorToSubtests()
+	if err != nil || status != RefactorGenerationStatusSuccess {
+		t.Fatalf("refactorToSubtests() = %s, %v, want success", status, err)
+	}
+	// Restore the original syntax, which is shared with other tests
+	t.Cleanup(func() {
+		for _, r := range refactored {
+			r.Cleanup()
+		}
+	})
+
+	if len(refactored) != 1 {
+		t.Fatalf("refactorToSubtests() refactored %d functions, want 1", len(refactored))
+	}
+	if got := refactored[0].RefactoredString; !strings.Contains(got, "t.Run(tests[i].name, func(t *testing.T) {") {
+		t.Errorf("refactored function doesn't name subtests using the indexed scenario:\n%s", got)
+	}
+}
+
+func TestIdentifyScenarioSetScope(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "scope")
+
+	tests := []struct {
+		name      string
+		scope     ScenarioScope
+		scenarios []string
+	}{
+		{"TestLocal", ScenarioScopeLocal, []string{"four"}},
+		{"TestFile", ScenarioScopeFile, []string{"three"}},
+		// Declared in another file of the package
+		{"TestShared", ScenarioScopePackage, []string{"zero", "one", "neg"}},
+		// Declared in the package under test, and used by the external test package
+		{"TestExternal", ScenarioScopePackage, []string{"#00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := analyzeFixtureFunc(t, funcs, tt.name)
+			ss := ar.ScenarioSet
+			if ss == nil || !ar.IsTableDriven() {
+				t.Fatal("test isn't table-driven")
+			}
+			if ss.Scope != tt.scope || ss.Construction != ScenarioConstructionLiteral {
+				t.Errorf("ScenarioSet has Scope = %s and Construction = %s, want %s and literal", ss.Scope, ss.Construction, tt.scope)
+			}
+			var names []string
+			for _, scenario := range ss.Scenarios {
+				names = append(names, scenario.Name)
+			}
+			if !slices.Equal(names, tt.scenarios) {
+				t.Errorf("found scenarios %q, want %q", names, tt.scenarios)
+			}
+		})
+	}
+}
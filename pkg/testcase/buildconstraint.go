@@ -0,0 +1,127 @@
+package testcase
+
+// Handles the build constraints that determine which build configurations include a test case.
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Operating systems and architectures that can be implied by file name suffixes like `_linux_arm64_test.go`,
+// matching the lists used by the `go/build` package
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true, "hurd": true, "illumos": true,
+		"ios": true, "js": true, "linux": true, "nacl": true, "netbsd": true, "openbsd": true, "plan9": true,
+		"solaris": true, "wasip1": true, "windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true, "arm64be": true,
+		"loong64": true, "mips": true, "mipsle": true, "mips64": true, "mips64le": true, "mips64p32": true,
+		"mips64p32le": true, "ppc": true, "ppc64": true, "ppc64le": true, "riscv": true, "riscv64": true, "s390": true,
+		"s390x": true, "sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// Return the build constraint that must be satisfied for the file to be included in a build, combining its
+// `//go:build` line with the operating system and architecture implied by its name.
+// Returns nil if the file is included in every build.
+func fileBuildConstraint(file *ast.File, filePath string) constraint.Expr {
+	var expr constraint.Expr
+
+	// Build constraints must appear before the package clause
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if !constraint.IsGoBuild(comment.Text) {
+				continue
+			}
+			if x, err := constraint.Parse(comment.Text); err == nil {
+				expr = x
+			}
+		}
+	}
+
+	for _, tag := range fileNameTags(filepath.Base(filePath)) {
+		if expr == nil {
+			expr = &constraint.TagExpr{Tag: tag}
+		} else {
+			expr = &constraint.AndExpr{X: expr, Y: &constraint.TagExpr{Tag: tag}}
+		}
+	}
+	return expr
+}
+
+// Return the operating system and architecture implied by a file name (e.g. `linux` and `arm64` for
+// `foo_linux_arm64_test.go`), following the same rules as the `go` command.
+func fileNameTags(name string) []string {
+	name, _, _ = strings.Cut(name, ".")
+
+	// The first element of the name is never a constraint, so `linux.go` applies to every platform
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return nil
+	}
+	elems := strings.Split(name[i:], "_")
+	if n := len(elems); n > 0 && elems[n-1] == "test" {
+		elems = elems[:n-1]
+	}
+
+	n := len(elems)
+	if n >= 2 && knownOS[elems[n-2]] && knownArch[elems[n-1]] {
+		return []string{elems[n-2], elems[n-1]}
+	}
+	if n >= 1 && (knownOS[elems[n-1]] || knownArch[elems[n-1]]) {
+		return []string{elems[n-1]}
+	}
+	return nil
+}
+
+// Return the build tags needed for `go test` to include the test's file on the current platform, which are the
+// custom tags (e.g. `integration`) in its build constraint. Returns an error if the file can't be included on the
+// current platform regardless of the tags, e.g. because it's specific to another operating system.
+func (tc *TestCase) executionTags() ([]string, error) {
+	if tc.BuildConstraint == "" {
+		return nil, nil
+	}
+	expr, err := constraint.Parse("//go:build " + tc.BuildConstraint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing build constraint %q: %w", tc.BuildConstraint, err)
+	}
+
+	// Try the default build context first, and then try enabling every custom tag in the constraint
+	ctxt := build.Default
+	dir, name := filepath.Split(tc.FilePath)
+	for _, tags := range [][]string{nil, customTags(expr)} {
+		ctxt.BuildTags = tags
+		match, err := ctxt.MatchFile(dir, name)
+		if err != nil {
+			return nil, fmt.Errorf("checking build constraints of %q: %w", tc.FilePath, err)
+		}
+		if match {
+			return tags, nil
+		}
+	}
+	return nil, fmt.Errorf("test file is excluded by build constraint %q on %s/%s", tc.BuildConstraint, ctxt.GOOS, ctxt.GOARCH)
+}
+
+// Return the sorted custom tags used in a build constraint, excluding tags that are set by the toolchain itself
+// like operating systems, architectures, compilers, and Go versions.
+func customTags(expr constraint.Expr) []string {
+	var tags []string
+	expr.Eval(func(tag string) bool {
+		if !knownOS[tag] && !knownArch[tag] && !strings.HasPrefix(tag, "go1.") && !slices.Contains([]string{"unix", "cgo", "gc", "gccgo"}, tag) {
+			tags = append(tags, tag)
+		}
+		return false
+	})
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
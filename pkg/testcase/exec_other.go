@@ -0,0 +1,8 @@
+//go:build !unix
+
+package testcase
+
+import "os/exec"
+
+// Process groups aren't supported on this platform, so the command is only stopped by its context as usual.
+func setProcessGroup(c *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package testcase
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Start the command in its own process group, and stop the entire group when the command's context is cancelled
+// so the compiled test binary started by `go test` is stopped too.
+func setProcessGroup(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
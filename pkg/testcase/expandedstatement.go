@@ -9,6 +9,7 @@ import (
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/maxgreen01/go-test-parser/pkg/asttools"
 	"golang.org/x/tools/go/ast/astutil"
@@ -155,8 +156,39 @@ type ExpressionDefinition struct {
 }
 
 // Memoization cache for FindDefinition to avoid redundant lookups.
-// Keys are strings formatted as "<position>-<project>-<package>-<testOnly>".
-var findDefinitionMemo = make(map[string]*ExpressionDefinition)
+// Keys are strings formatted as "<filename>:<offset>-<import path>-<package variant>-<testOnly>", which don't depend
+// on the FileSet used to load the package, since each batch of packages is loaded using a new one.
+// Guarded by `findDefinitionMu` because test cases may be analyzed concurrently.
+// The cached definitions refer to the syntax of loaded packages, so the cache must be cleared using ReleaseCaches
+// once those packages are no longer used.
+var (
+	findDefinitionMemo = make(map[string]*ExpressionDefinition)
+	findDefinitionMu   sync.Mutex
+)
+
+// Thread-safe helpers for accessing the FindDefinition memoization cache
+func loadDefinitionMemo(key string) (*ExpressionDefinition, bool) {
+	findDefinitionMu.Lock()
+	defer findDefinitionMu.Unlock()
+	def, ok := findDefinitionMemo[key]
+	return def, ok
+}
+
+func storeDefinitionMemo(key string, def *ExpressionDefinition) {
+	findDefinitionMu.Lock()
+	defer findDefinitionMu.Unlock()
+	findDefinitionMemo[key] = def
+}
+
+// Clear the caches that refer to the syntax or type information of loaded packages, so the packages can be garbage
+// collected. Should be called after every loaded package has been analyzed, before loading the next packages.
+func ReleaseCaches() {
+	findDefinitionMu.Lock()
+	defer findDefinitionMu.Unlock()
+	clear(findDefinitionMemo)
+	ginkgoRunnerMemo.clear()
+	suiteMemo.clear()
+}
 
 // Return the AST definition and of the expression within the specified TestCase's package, if it exists.
 // Also returns the AST file that contains the definition if it is successfully found, or nil in all other cases.
@@ -206,8 +238,13 @@ func FindDefinition(expr ast.Expr, tc *TestCase, testOnly bool) (*ExpressionDefi
 	}
 
 	// Check the memoization cache to see if the definition has already been found
-	cacheKey := fmt.Sprintf("%d-%s-%s-%v", pos, tc.PackageName, tc.ProjectName, testOnly)
-	if cached, ok := findDefinitionMemo[cacheKey]; ok {
+	fset := tc.FileSet()
+	if fset == nil {
+		return nil, fmt.Errorf("could not find definition for identifier %q because FileSet is nil", ident.Name)
+	}
+	position := fset.PositionFor(pos, false) // ignore `//line` directives, which may point to other files
+	cacheKey := fmt.Sprintf("%s:%d-%s-%s-%v", position.Filename, position.Offset, tc.GetImportPath(), tc.PackageVariant, testOnly)
+	if cached, ok := loadDefinitionMemo(cacheKey); ok {
 		// Definition already found, so return it
 		return cached, nil
 	}
@@ -220,14 +257,10 @@ func FindDefinition(expr ast.Expr, tc *TestCase, testOnly bool) (*ExpressionDefi
 
 	if testOnly {
 		// Only expand definitions inside test files
-		fset := tc.FileSet()
-		if fset == nil {
-			return nil, fmt.Errorf("could not check definition file path for identifier %q because FileSet is nil", ident.Name)
-		}
-		if !strings.HasSuffix(fset.Position(definitionFile.FileStart).Filename, "_test.go") {
+		if !strings.HasSuffix(position.Filename, "_test.go") {
 			// Definition not in a test file
 			slog.Debug("Ignoring identifier definition found outside a test file", "identifier", ident.Name, "test", tc)
-			findDefinitionMemo[cacheKey] = nil // Store the result in the memoization cache
+			storeDefinitionMemo(cacheKey, nil) // Store the result in the memoization cache
 			return nil, nil
 		}
 	}
@@ -249,7 +282,7 @@ func FindDefinition(expr ast.Expr, tc *TestCase, testOnly bool) (*ExpressionDefi
 		definition := &ExpressionDefinition{Node: path[1], File: definitionFile}
 		slog.Debug("Found definition for identifier", "identifier", ident.Name, "position", definition.Node.Pos(), "test", tc)
 
-		findDefinitionMemo[cacheKey] = definition // Store the definition in the memoization cache
+		storeDefinitionMemo(cacheKey, definition) // Store the definition in the memoization cache
 		return definition, nil
 	}
 
@@ -0,0 +1,87 @@
+package testcase
+
+// Helpers for finding the declarations in the fixture packages in `test/testdata`.
+
+import (
+	"go/ast"
+	"testing"
+
+	"github.com/maxgreen01/go-test-parser/test/testdata"
+	"golang.org/x/tools/go/packages"
+)
+
+// A top-level function declaration in a fixture package, along with the syntax data needed to create a TestCase.
+type fixtureFunc struct {
+	decl *ast.FuncDecl
+	file *ast.File
+	pkg  *packages.Package
+}
+
+// The packages of every fixture loaded so far, keyed by the fixture's directory.
+// Loading packages is slow, so each fixture is only loaded once even if it's used by multiple tests.
+var fixturePackages = make(map[string][]*packages.Package)
+
+// Load the package variants of the fixture in the specified directory (see testdata.LoadFixture).
+func loadFixture(t *testing.T, dir string) []*packages.Package {
+	t.Helper()
+	if pkgs, ok := fixturePackages[dir]; ok {
+		return pkgs
+	}
+	pkgs := testdata.LoadFixture(t, dir)
+	fixturePackages[dir] = pkgs
+	return pkgs
+}
+
+// Load the fixture package in the specified directory, and return its function declarations keyed by name.
+// Methods are keyed like `Type.Method`, ignoring whether the receiver is a pointer.
+func loadFixtureFuncs(t *testing.T, dir string) map[string]fixtureFunc {
+	t.Helper()
+	funcs := make(map[string]fixtureFunc)
+	for _, pkg := range loadFixture(t, dir) {
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				key := fn.Name.Name
+				if fn.Recv != nil && len(fn.Recv.List) > 0 {
+					recv := fn.Recv.List[0].Type
+					if star, ok := recv.(*ast.StarExpr); ok {
+						recv = star.X
+					}
+					if ident, ok := recv.(*ast.Ident); ok {
+						key = ident.Name + "." + key
+					}
+				}
+				if _, found := funcs[key]; found {
+					t.Fatalf("function %q is declared more than once in fixture %q", key, dir)
+				}
+				funcs[key] = fixtureFunc{decl: fn, file: file, pkg: pkg}
+			}
+		}
+	}
+	return funcs
+}
+
+// Return the function with the specified name, failing the test if the fixture doesn't declare it.
+func findFixtureFunc(t *testing.T, funcs map[string]fixtureFunc, name string) fixtureFunc {
+	t.Helper()
+	fn, ok := funcs[name]
+	if !ok {
+		t.Fatalf("function %q not found in fixture", name)
+	}
+	return fn
+}
+
+// Analyze the test function with the specified name from the fixture's declarations, failing the test if it's missing.
+func analyzeFixtureFunc(t *testing.T, funcs map[string]fixtureFunc, name string) *AnalysisResult {
+	t.Helper()
+	fn := findFixtureFunc(t, funcs, name)
+	tc := CreateTestCase(fn.decl, fn.file, fn.pkg, "fixtures")
+	ar := Analyze(&tc)
+	if ar == nil {
+		t.Fatalf("Analyze() = nil for %q", name)
+	}
+	return ar
+}
@@ -0,0 +1,487 @@
+package testcase
+
+// Handles extracting the spec tree of Ginkgo test suites, where a single `TestXxx` function calls `RunSpecs` and the
+// actual tests are defined by nested `Describe`, `Context`, `It`, `DescribeTable`, and `Entry` calls.
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/maxgreen01/go-test-parser/pkg/asttools"
+	"golang.org/x/tools/go/packages"
+)
+
+// The import path prefix shared by every major version of Ginkgo, e.g. `github.com/onsi/ginkgo/v2`
+const ginkgoPathPrefix = "github.com/onsi/ginkgo"
+
+// Represents a node in the spec tree of a Ginkgo test suite, i.e. a call to one of Ginkgo's container, subject,
+// or table functions. Setup nodes like `BeforeEach` are not included.
+type GinkgoNode struct {
+	Type     GinkgoNodeType
+	Func     string // the name of the Ginkgo function that defines the node, e.g. "Describe" or "FIt"
+	Text     string // the text describing the node, or the source code of the expression if it isn't a string literal
+	Pending  bool   // whether the node is marked as pending (e.g. using `PIt` or `XDescribe`), so it won't be run
+	Focused  bool   // whether the node is marked as focused (e.g. using `FIt`)
+	Parent   *GinkgoNode
+	Children []*GinkgoNode
+
+	Call *ast.CallExpr // the call that defines the node
+
+	// The function containing the node's code: the body of a spec, the function run for each entry of a table,
+	// or the body of a container. A `FuncDecl` is created for function literals so that specs can be analyzed
+	// like regular test functions. Nil for entries, or if the function couldn't be found.
+	funcDecl *ast.FuncDecl
+}
+
+// Represents the type of a node in the spec tree of a Ginkgo test suite
+type GinkgoNodeType int
+
+const (
+	GinkgoContainerNode GinkgoNodeType = iota // a container like `Describe`, `Context`, or `When`
+	GinkgoSpecNode                            // a subject like `It` or `Specify`, which is a single spec
+	GinkgoTableNode                           // a `DescribeTable`, whose entries are each run as a separate spec
+	GinkgoEntryNode                           // an `Entry` of a table
+)
+
+func (t GinkgoNodeType) String() string {
+	switch t {
+	case GinkgoContainerNode:
+		return "container"
+	case GinkgoSpecNode:
+		return "spec"
+	case GinkgoTableNode:
+		return "table"
+	case GinkgoEntryNode:
+		return "entry"
+	default:
+		return "unknown"
+	}
+}
+
+func (t GinkgoNodeType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// The Ginkgo functions that define each type of node, without the "F", "P", or "X" prefixes used to focus or skip them
+var ginkgoNodeFuncs = map[string]GinkgoNodeType{
+	"Describe":             GinkgoContainerNode,
+	"Context":              GinkgoContainerNode,
+	"When":                 GinkgoContainerNode,
+	"DescribeTableSubtree": GinkgoContainerNode,
+	"It":                   GinkgoSpecNode,
+	"Specify":              GinkgoSpecNode,
+	"DescribeTable":        GinkgoTableNode,
+	"Entry":                GinkgoEntryNode,
+}
+
+// Find the Ginkgo specs defined in the file, i.e. every `It` and `DescribeTable` node of its spec tree, in the order
+// they're defined. The rest of the tree can be reached using each spec's `Parent`. Specs without a function (which
+// are always pending) are skipped. Returns nil if the file doesn't import Ginkgo.
+//
+// Only nodes defined in top-level variable declarations (like `var _ = Describe(...)`) and `init` functions are
+// found, along with the nodes nested inside them. Nodes defined by calling other functions aren't detected.
+func FindGinkgoSpecs(file *ast.File, pkg *packages.Package) []*GinkgoNode {
+	if file == nil || pkg == nil {
+		return nil
+	}
+	imp, ok := findGinkgoImport(file)
+	if !ok {
+		return nil
+	}
+	e := ginkgoExtractor{imp: imp, info: pkg.TypesInfo, pkg: pkg}
+
+	var roots []*GinkgoNode
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				if valueSpec, ok := spec.(*ast.ValueSpec); ok {
+					for _, value := range valueSpec.Values {
+						roots = append(roots, e.findNodes(value, nil)...)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.Name == "init" && decl.Body != nil {
+				roots = append(roots, e.findNodes(decl.Body, nil)...)
+			}
+		}
+	}
+
+	var specs []*GinkgoNode
+	for _, root := range roots {
+		for node := range root.All() {
+			if (node.Type == GinkgoSpecNode || node.Type == GinkgoTableNode) && node.funcDecl != nil {
+				specs = append(specs, node)
+			}
+		}
+	}
+	return specs
+}
+
+// Return an iterator over the node and all of its descendants, in depth-first order.
+func (n *GinkgoNode) All() iter.Seq[*GinkgoNode] {
+	return func(yield func(*GinkgoNode) bool) {
+		n.walk(yield)
+	}
+}
+
+func (n *GinkgoNode) walk(yield func(*GinkgoNode) bool) bool {
+	if !yield(n) {
+		return false
+	}
+	for _, child := range n.Children {
+		if !child.walk(yield) {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the texts of the node and each of its ancestors, starting from the root of the spec tree.
+func (n *GinkgoNode) Path() []string {
+	var path []string
+	for node := n; node != nil; node = node.Parent {
+		path = append([]string{node.Text}, path...)
+	}
+	return path
+}
+
+// Return the full text of the node, which is the texts of its ancestors and itself separated by spaces,
+// matching the text that Ginkgo uses to identify specs (e.g. when using `--ginkgo.focus`).
+func (n *GinkgoNode) FullText() string {
+	return strings.Join(n.Path(), " ")
+}
+
+// Return whether the node or any of its ancestors is marked as pending.
+func (n *GinkgoNode) IsPending() bool {
+	for node := n; node != nil; node = node.Parent {
+		if node.Pending {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the entries of a table node.
+func (n *GinkgoNode) Entries() []*GinkgoNode {
+	var entries []*GinkgoNode
+	for _, child := range n.Children {
+		if child.Type == GinkgoEntryNode {
+			entries = append(entries, child)
+		}
+	}
+	return entries
+}
+
+// Represents how a file refers to the Ginkgo package
+type ginkgoImport struct {
+	name string // the name used to refer to the package, or empty if it's dot-imported
+	dot  bool   // whether the package is dot-imported, which is the usual way of using Ginkgo
+}
+
+// Return how the file imports Ginkgo, and whether it's imported at all.
+func findGinkgoImport(file *ast.File) (ginkgoImport, bool) {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || !isGinkgoPath(path) {
+			continue
+		}
+		switch {
+		case spec.Name == nil:
+			return ginkgoImport{name: "ginkgo"}, true
+		case spec.Name.Name == ".":
+			return ginkgoImport{dot: true}, true
+		case spec.Name.Name != "_":
+			return ginkgoImport{name: spec.Name.Name}, true
+		}
+	}
+	return ginkgoImport{}, false
+}
+
+// Return whether the import path is the main Ginkgo package, e.g. `github.com/onsi/ginkgo/v2`.
+func isGinkgoPath(path string) bool {
+	rest, ok := strings.CutPrefix(path, ginkgoPathPrefix)
+	if !ok {
+		return false
+	}
+	if rest == "" {
+		return true
+	}
+	version, ok := strings.CutPrefix(rest, "/v")
+	if !ok {
+		return false
+	}
+	_, err := strconv.Atoi(version)
+	return err == nil
+}
+
+// Holds the information needed to extract Ginkgo nodes from a file
+type ginkgoExtractor struct {
+	imp  ginkgoImport
+	info *types.Info
+	pkg  *packages.Package
+}
+
+// Find the outermost Ginkgo nodes within the AST node, adding them as children of `parent` (if it isn't nil)
+// and recursively finding the nodes nested inside them.
+func (e *ginkgoExtractor) findNodes(root ast.Node, parent *GinkgoNode) []*GinkgoNode {
+	var nodes []*GinkgoNode
+	ast.Inspect(root, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		node := e.newNode(call, parent)
+		if node == nil {
+			return true
+		}
+		nodes = append(nodes, node)
+		return false // Nested nodes were already found when creating the node
+	})
+	if parent != nil {
+		parent.Children = append(parent.Children, nodes...)
+	}
+	return nodes
+}
+
+// Create the Ginkgo node defined by the call, including all of its children, or return nil if it doesn't call
+// one of Ginkgo's node functions.
+func (e *ginkgoExtractor) newNode(call *ast.CallExpr, parent *GinkgoNode) *GinkgoNode {
+	name := e.funcName(call)
+	if name == "" {
+		return nil
+	}
+	base, pending, focused := name, false, false
+	if _, ok := ginkgoNodeFuncs[name]; !ok && strings.ContainsRune("FPX", rune(name[0])) {
+		base = name[1:]
+		pending = name[0] == 'P' || name[0] == 'X'
+		focused = name[0] == 'F'
+	}
+	typ, ok := ginkgoNodeFuncs[base]
+	if !ok {
+		return nil
+	}
+
+	node := &GinkgoNode{
+		Type:    typ,
+		Func:    name,
+		Pending: pending,
+		Focused: focused,
+		Parent:  parent,
+		Call:    call,
+	}
+	if len(call.Args) > 0 {
+		node.Text = e.text(call.Args[0])
+	}
+
+	// The remaining arguments may include decorators like `Pending` or `Label(...)`, followed by the node's function
+	var args []ast.Expr
+	if len(call.Args) > 0 {
+		args = call.Args[1:]
+	}
+	for i, arg := range args {
+		if ident, ok := arg.(*ast.Ident); ok && e.isGinkgoObject(ident, "Pending") {
+			node.Pending = true
+		}
+		if node.funcDecl != nil || typ == GinkgoEntryNode {
+			continue
+		}
+		if fn := e.funcDeclOf(arg, name); fn != nil {
+			node.funcDecl = fn
+			if typ == GinkgoContainerNode {
+				e.findNodes(fn.Body, node)
+			}
+			if typ == GinkgoTableNode {
+				// The entries are passed after the table's function
+				for _, entry := range args[i+1:] {
+					e.findNodes(entry, node)
+				}
+			}
+		}
+	}
+	return node
+}
+
+// Return the name of the Ginkgo function called by the expression, or an empty string if it doesn't call a
+// function from the Ginkgo package.
+func (e *ginkgoExtractor) funcName(call *ast.CallExpr) string {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if !e.imp.dot {
+			return ""
+		}
+		ident = fun
+	case *ast.SelectorExpr:
+		if e.imp.dot || !asttools.MatchSelectorExpr(fun, e.imp.name, fun.Sel.Name) {
+			return ""
+		}
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	if !e.isGinkgoObject(ident, ident.Name) {
+		return ""
+	}
+	return ident.Name
+}
+
+// Return whether the identifier refers to the object with the specified name in the Ginkgo package. Without type
+// information, identifiers are assumed to refer to Ginkgo objects if they have the right name.
+func (e *ginkgoExtractor) isGinkgoObject(ident *ast.Ident, name string) bool {
+	if ident.Name != name {
+		return false
+	}
+	if e.info == nil {
+		return true
+	}
+	obj := e.info.Uses[ident]
+	if obj == nil {
+		return true // Missing type information, e.g. because the package has errors
+	}
+	return obj.Pkg() != nil && isGinkgoPath(obj.Pkg().Path())
+}
+
+// Return the text described by the expression, which is the value of a string literal, or otherwise the expression's
+// source code (e.g. for calls to `fmt.Sprintf`).
+func (e *ginkgoExtractor) text(expr ast.Expr) string {
+	if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+		if text, err := strconv.Unquote(lit.Value); err == nil {
+			return text
+		}
+	}
+	return asttools.NodeToString(expr, e.pkg.Fset)
+}
+
+// Return a function declaration for the function passed to a Ginkgo node, or nil if the expression isn't a function.
+// Function literals are wrapped in a new declaration named after the Ginkgo function, and identifiers referring to
+// top-level functions in the package return the original declaration.
+func (e *ginkgoExtractor) funcDeclOf(expr ast.Expr, name string) *ast.FuncDecl {
+	switch fn := expr.(type) {
+	case *ast.FuncLit:
+		return &ast.FuncDecl{
+			Name: &ast.Ident{NamePos: fn.Pos(), Name: name},
+			Type: fn.Type,
+			Body: fn.Body,
+		}
+	case *ast.Ident:
+		for _, file := range e.pkg.Syntax {
+			for _, decl := range file.Decls {
+				if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.Name == fn.Name && funcDecl.Body != nil {
+					return funcDecl
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Represents the test function that runs a package's Ginkgo specs by calling `RunSpecs`
+type ginkgoRunner struct {
+	name        string // the name of the test function, or empty if none was found
+	description string // the description of the suite passed to `RunSpecs`
+}
+
+// Memoization cache for findGinkgoRunner, since every spec in a package has the same runner
+var ginkgoRunnerMemo packageMemo[ginkgoRunner]
+
+// Find the test function that runs the package's Ginkgo specs by calling `RunSpecs`, along with the description of
+// the suite passed to it. Returns an empty ginkgoRunner if no such function was found.
+func findGinkgoRunner(pkg *packages.Package) ginkgoRunner {
+	var runner ginkgoRunner
+	for _, file := range pkg.Syntax {
+		imp, ok := findGinkgoImport(file)
+		if !ok {
+			continue
+		}
+		e := ginkgoExtractor{imp: imp, info: pkg.TypesInfo, pkg: pkg}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			if kind, valid, _ := ClassifyFunction(fn); !valid || kind != TestKindTest {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || runner.name != "" || !strings.HasPrefix(e.funcName(call), "RunSpecs") {
+					return runner.name == ""
+				}
+				runner.name = fn.Name.Name
+				if len(call.Args) > 1 {
+					runner.description = e.text(call.Args[1])
+				}
+				return false
+			})
+			if runner.name != "" {
+				return runner
+			}
+		}
+	}
+	return ginkgoRunner{}
+}
+
+// Create a new TestCase for a Ginkgo spec (i.e. an `It` or `DescribeTable` node), named after the spec's full text.
+// The function passed to the node is treated as the test function, and the test function that calls `RunSpecs`
+// is recorded as the suite runner, which is only searched for once per package.
+func CreateGinkgoTestCase(spec *GinkgoNode, file *ast.File, pkg *packages.Package, project string) TestCase {
+	if spec == nil || spec.funcDecl == nil {
+		return TestCase{}
+	}
+	tc := CreateTestCase(spec.funcDecl, file, pkg, project)
+	tc.TestName = spec.FullText()
+	tc.Kind = TestKindGinkgo
+	tc.SpecPath = spec.Path()
+	runner := ginkgoRunnerMemo.get(pkg, findGinkgoRunner)
+	tc.SuiteRunner, tc.Suite = runner.name, runner.description
+	tc.spec = spec
+	return tc
+}
+
+// Build the ScenarioSet of a Ginkgo table, whose scenarios are the `Entry` calls and whose fields are the parameters
+// of the function run for each entry. The entries' descriptions are used as the scenario names.
+func identifyGinkgoTable(tc *TestCase, table *GinkgoNode) *ScenarioSet {
+	ss := &ScenarioSet{
+		TestCase:      tc,
+		DataStructure: ScenarioGinkgoEntriesDS,
+		Runner:        &ast.ExprStmt{X: table.Call},
+		NameField:     "entry description",
+	}
+
+	// Create a struct type with a field for each parameter of the table's function
+	var fields []*types.Var
+	for _, field := range table.funcDecl.Type.Params.List {
+		typ := tc.TypeOf(field.Type)
+		if typ == nil {
+			typ = types.Typ[types.Invalid]
+		}
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent("_")}
+		}
+		for _, name := range names {
+			fields = append(fields, types.NewField(name.Pos(), nil, name.Name, typ, false))
+		}
+	}
+	ss.ScenarioType = types.NewStruct(fields, nil)
+
+	for i, entry := range table.Entries() {
+		ss.Scenarios = append(ss.Scenarios, ss.newScenario(i, entry.Call))
+	}
+	if len(ss.Scenarios) > 0 {
+		ss.Construction = ScenarioConstructionLiteral
+		ss.Scope = ScenarioScopeLocal
+	}
+	ss.Analyze()
+	return ss
+}
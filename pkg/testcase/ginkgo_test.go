@@ -0,0 +1,130 @@
+package testcase
+
+import (
+	"go/constant"
+	"slices"
+	"testing"
+)
+
+// Return a TestCase for every Ginkgo spec in the fixture, keyed by the spec's full text.
+func loadGinkgoTestCases(t *testing.T, dir string) map[string]*TestCase {
+	t.Helper()
+	testCases := make(map[string]*TestCase)
+	for _, pkg := range loadFixture(t, dir) {
+		for _, file := range pkg.Syntax {
+			for _, spec := range FindGinkgoSpecs(file, pkg) {
+				tc := CreateGinkgoTestCase(spec, file, pkg, dir)
+				if _, found := testCases[tc.TestName]; found {
+					t.Fatalf("spec %q is defined more than once in fixture %q", tc.TestName, dir)
+				}
+				testCases[tc.TestName] = &tc
+			}
+		}
+	}
+	return testCases
+}
+
+func TestFindGinkgoSpecs(t *testing.T) {
+	testCases := loadGinkgoTestCases(t, "specs")
+
+	tests := []struct {
+		text     string
+		nodeType GinkgoNodeType
+		fn       string
+		path     []string
+		pending  bool
+		focused  bool
+	}{
+		{"Calc when adding adds positives", GinkgoSpecNode, "It", []string{"Calc", "when adding", "adds positives"}, false, false},
+		{"Calc when adding adds negatives", GinkgoSpecNode, "FIt", []string{"Calc", "when adding", "adds negatives"}, false, true},
+		{"Calc when adding adds (positives)", GinkgoSpecNode, "It", []string{"Calc", "when adding", "adds (positives)"}, false, false},
+		{"Calc when adding adds [positives]", GinkgoSpecNode, "It", []string{"Calc", "when adding", "adds [positives]"}, false, false},
+		{"Calc pending is skipped", GinkgoSpecNode, "It", []string{"Calc", "pending", "is skipped"}, true, false},
+		{"Calc disabled is disabled", GinkgoSpecNode, "It", []string{"Calc", "disabled", "is disabled"}, true, false},
+		{"Calc sums", GinkgoTableNode, "DescribeTable", []string{"Calc", "sums"}, false, false},
+		{"Calc uses a named function", GinkgoSpecNode, "It", []string{"Calc", "uses a named function"}, false, false},
+		{`Calc fmt.Sprintf("computes %d", 1)`, GinkgoSpecNode, "Specify", []string{"Calc", `fmt.Sprintf("computes %d", 1)`}, false, false},
+		{"Init is defined in init", GinkgoSpecNode, "It", []string{"Init", "is defined in init"}, false, false},
+		{"Alias uses a named import", GinkgoSpecNode, "It", []string{"Alias", "uses a named import"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tc, ok := testCases[tt.text]
+			if !ok {
+				t.Fatalf("spec %q not found", tt.text)
+			}
+			spec := tc.spec
+			if spec.Type != tt.nodeType || spec.Func != tt.fn {
+				t.Errorf("spec is a %s defined by %s, want a %s defined by %s", spec.Type, spec.Func, tt.nodeType, tt.fn)
+			}
+			if spec.IsPending() != tt.pending || spec.Focused != tt.focused {
+				t.Errorf("spec has pending = %v and focused = %v, want %v and %v", spec.IsPending(), spec.Focused, tt.pending, tt.focused)
+			}
+			if !slices.Equal(tc.SpecPath, tt.path) {
+				t.Errorf("SpecPath = %q, want %q", tc.SpecPath, tt.path)
+			}
+			if tc.Kind != TestKindGinkgo || tc.Suite != "Specs Suite" || tc.SuiteRunner != "TestSpecs" {
+				t.Errorf("test case has Kind = %s, Suite = %q, and SuiteRunner = %q, want ginkgo, %q, and %q", tc.Kind, tc.Suite, tc.SuiteRunner, "Specs Suite", "TestSpecs")
+			}
+		})
+	}
+
+	// Specs without a function (like `PIt("text")`) aren't test cases
+	if len(testCases) != len(tests) {
+		var names []string
+		for name := range testCases {
+			names = append(names, name)
+		}
+		t.Errorf("found %d specs, want %d: %q", len(testCases), len(tests), names)
+	}
+
+	// Specs whose texts only differ in punctuation must still be saved to different files
+	paths := make(map[string]string)
+	for name, tc := range testCases {
+		path := tc.GetJSONFilePath("")
+		if other, found := paths[path]; found {
+			t.Errorf("specs %q and %q have the same JSON file path %q", name, other, path)
+		}
+		paths[path] = name
+	}
+}
+
+func TestGinkgoTable(t *testing.T) {
+	tc, ok := loadGinkgoTestCases(t, "specs")["Calc sums"]
+	if !ok {
+		t.Fatal("table spec not found")
+	}
+	ar := Analyze(tc)
+	if ar == nil || !ar.IsTableDriven() {
+		t.Fatal("table spec isn't table-driven")
+	}
+	ss := ar.ScenarioSet
+	if ss.DataStructure != ScenarioGinkgoEntriesDS || ss.Construction != ScenarioConstructionLiteral || ss.Scope != ScenarioScopeLocal {
+		t.Errorf("ScenarioSet has DataStructure = %s, Construction = %s, and Scope = %s, want ginkgoEntries, literal, and local", ss.DataStructure, ss.Construction, ss.Scope)
+	}
+
+	want := []struct {
+		name string
+		want int64
+	}{
+		{"zeros", 0},
+		{"ones", 2},
+		{"#02", 4}, // The description isn't a string literal
+	}
+	if len(ss.Scenarios) != len(want) {
+		t.Fatalf("found %d scenarios, want %d", len(ss.Scenarios), len(want))
+	}
+	for i, scenario := range ss.Scenarios {
+		if scenario.Name != want[i].name {
+			t.Errorf("scenario %d has name %q, want %q", i, scenario.Name, want[i].name)
+		}
+		field := scenario.Fields["want"]
+		if field == nil || field.Value == nil {
+			t.Errorf("scenario %q has no constant value for the `want` parameter", scenario.Name)
+			continue
+		}
+		if got, exact := constant.Int64Val(field.Value); !exact || got != want[i].want {
+			t.Errorf("scenario %q has want = %s, expected %d", scenario.Name, field.Value, want[i].want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package testcase
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"log/slog"
+	"strconv"
+)
+
+// Represents the reason why a function that looks like a test function won't be run by `go test` (or breaks the build
+// of the package's tests), which usually indicates a mistake.
+type NearMissReason int
+
+const (
+	NearMissNone            NearMissReason = iota // The function is a valid test function, or doesn't look like one at all
+	NearMissLowercaseName                         // The prefix is followed by a lowercase letter, like `Testfoo`, so `go test` ignores it
+	NearMissLowercasePrefix                       // The prefix has the wrong case, like `testFoo`, so `go test` ignores it
+	NearMissMethod                                // The function is a method, like `func (s *Suite) TestFoo()`
+	NearMissTypeParams                            // The function has generic type parameters
+	NearMissResults                               // The function returns values
+	NearMissParamCount                            // The function has the wrong number of parameters for its kind
+	NearMissParamType                             // The function's parameter has the wrong type for its kind, like `TestFoo(t testing.TB)`
+)
+
+// Return the NearMissReason corresponding to the given string.
+func NearMissReasonFromString(reason string) NearMissReason {
+	switch reason {
+	case "lowercaseName":
+		return NearMissLowercaseName
+	case "lowercasePrefix":
+		return NearMissLowercasePrefix
+	case "method":
+		return NearMissMethod
+	case "typeParams":
+		return NearMissTypeParams
+	case "results":
+		return NearMissResults
+	case "paramCount":
+		return NearMissParamCount
+	case "paramType":
+		return NearMissParamType
+	default:
+		return NearMissNone
+	}
+}
+
+func (r NearMissReason) String() string {
+	switch r {
+	case NearMissNone:
+		return "none"
+	case NearMissLowercaseName:
+		return "lowercaseName"
+	case NearMissLowercasePrefix:
+		return "lowercasePrefix"
+	case NearMissMethod:
+		return "method"
+	case NearMissTypeParams:
+		return "typeParams"
+	case NearMissResults:
+		return "results"
+	case NearMissParamCount:
+		return "paramCount"
+	case NearMissParamType:
+		return "paramType"
+	default:
+		return "unknown"
+	}
+}
+
+// Return a human-readable description of the reason.
+func (r NearMissReason) Description() string {
+	switch r {
+	case NearMissLowercaseName:
+		return "name continues with a lowercase letter after the prefix, so it is ignored by go test"
+	case NearMissLowercasePrefix:
+		return "name prefix has the wrong case, so it is ignored by go test"
+	case NearMissMethod:
+		return "test functions cannot be methods"
+	case NearMissTypeParams:
+		return "test functions cannot have type parameters"
+	case NearMissResults:
+		return "test functions cannot return values"
+	case NearMissParamCount:
+		return "wrong number of parameters"
+	case NearMissParamType:
+		return "wrong parameter type"
+	default:
+		return ""
+	}
+}
+
+// Return every NearMissReason other than NearMissNone, in order.
+func AllNearMissReasons() []NearMissReason {
+	return []NearMissReason{
+		NearMissLowercaseName, NearMissLowercasePrefix, NearMissMethod, NearMissTypeParams,
+		NearMissResults, NearMissParamCount, NearMissParamType,
+	}
+}
+
+func (r NearMissReason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *NearMissReason) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*r = NearMissReasonFromString(str)
+	return nil
+}
+
+// Represents a function that looks like a test function, but isn't a valid one.
+type NearMiss struct {
+	Name        string         `json:"name"`
+	Kind        TestKind       `json:"kind"` // the kind of test function that the function resembles
+	Reason      NearMissReason `json:"reason"`
+	PackageName string         `json:"package"`
+	FilePath    string         `json:"filePath"`
+	Line        int            `json:"line"`
+	ProjectName string         `json:"project"`
+}
+
+// Return a NearMiss describing why the function isn't a valid test function, or nil if it's valid or doesn't look
+// like a test function at all. Functions outside `_test.go` files are never near misses, since they can't be tests
+// (e.g. a method like `func (c *Client) TestConnection() error`).
+func FindNearMiss(funcDecl *ast.FuncDecl, file *ast.File, fset *token.FileSet, project string) *NearMiss {
+	if !IsTestFile(file, fset) {
+		return nil
+	}
+	kind, matched, reason, _ := classifyFunction(funcDecl)
+	if !matched || reason == NearMissNone {
+		return nil
+	}
+	pos := fset.Position(funcDecl.Pos())
+	slog.Debug("Found near-miss test function", "name", funcDecl.Name.Name, "reason", reason, "file", pos.Filename)
+	return &NearMiss{
+		Name:        funcDecl.Name.Name,
+		Kind:        kind,
+		Reason:      reason,
+		PackageName: file.Name.Name,
+		FilePath:    pos.Filename,
+		Line:        pos.Line,
+		ProjectName: project,
+	}
+}
+
+// Return the headers for the CSV representation of the NearMiss.
+func (nm *NearMiss) GetCSVHeaders() []string {
+	return []string{
+		"project",
+		"filePath",
+		"line",
+		"package",
+		"name",
+		"kind",
+		"reason",
+		"description",
+	}
+}
+
+// Encode the NearMiss as a CSV row, returning the encoded data corresponding to the headers in `GetCSVHeaders()`.
+func (nm *NearMiss) EncodeAsCSV() []string {
+	return []string{
+		nm.ProjectName,
+		nm.FilePath,
+		strconv.Itoa(nm.Line),
+		nm.PackageName,
+		nm.Name,
+		nm.Kind.String(),
+		nm.Reason.String(),
+		nm.Reason.Description(),
+	}
+}
@@ -0,0 +1,73 @@
+package testcase
+
+import "testing"
+
+func TestFindNearMiss(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "nearmiss")
+
+	tests := []struct {
+		name   string
+		kind   TestKind
+		reason NearMissReason // NearMissNone if the function isn't a near miss
+	}{
+		{"TestValid", TestKindTest, NearMissNone},
+		{"Testlower", TestKindTest, NearMissLowercaseName},
+		{"Benchmarkfoo", TestKindBenchmark, NearMissLowercaseName},
+		{"Exampleadd", TestKindExample, NearMissLowercaseName},
+		{"testLower", TestKindTest, NearMissLowercasePrefix},
+		{"benchmarkLower", TestKindBenchmark, NearMissLowercasePrefix},
+		{"mySuite.TestMethod", TestKindTest, NearMissMethod},
+		{"TestGeneric", TestKindTest, NearMissTypeParams},
+		{"TestResults", TestKindTest, NearMissResults},
+		{"TestNoParams", TestKindTest, NearMissParamCount},
+		{"TestTwoParams", TestKindTest, NearMissParamCount},
+		{"TestSharedParams", TestKindTest, NearMissParamCount},
+		{"ExampleParams", TestKindExample, NearMissParamCount},
+		{"TestInterface", TestKindTest, NearMissParamType},
+		{"TestWrongPointer", TestKindTest, NearMissParamType},
+		{"FuzzWrongPointer", TestKindFuzz, NearMissParamType},
+		{"TestMain", TestKindMain, NearMissParamType},
+		{"Testing", TestKindTest, NearMissNone},
+		{"testHelper", TestKindTest, NearMissNone},
+		{"setup", TestKindTest, NearMissNone},
+		{"Client.TestConnection", TestKindTest, NearMissNone},
+		{"Testhelper", TestKindTest, NearMissNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := findFixtureFunc(t, funcs, tt.name)
+			nm := FindNearMiss(fn.decl, fn.file, fn.pkg.Fset, "nearmiss")
+			if tt.reason == NearMissNone {
+				if nm != nil {
+					t.Errorf("FindNearMiss() = %s (%s), want nil", nm.Reason, nm.Kind)
+				}
+				return
+			}
+			if nm == nil {
+				t.Fatalf("FindNearMiss() = nil, want %s (%s)", tt.reason, tt.kind)
+			}
+			if nm.Reason != tt.reason || nm.Kind != tt.kind {
+				t.Errorf("FindNearMiss() = %s (%s), want %s (%s)", nm.Reason, nm.Kind, tt.reason, tt.kind)
+			}
+			if want := fn.pkg.Fset.Position(fn.decl.Pos()).Line; nm.Line != want {
+				t.Errorf("FindNearMiss().Line = %d, want %d", nm.Line, want)
+			}
+
+			// Near misses are never valid test functions
+			if _, valid, _ := ClassifyFunction(fn.decl); valid {
+				t.Error("ClassifyFunction() reports a near miss as valid")
+			}
+		})
+	}
+}
+
+func TestNearMissReasonStrings(t *testing.T) {
+	for _, reason := range AllNearMissReasons() {
+		if got := NearMissReasonFromString(reason.String()); got != reason {
+			t.Errorf("NearMissReasonFromString(%q) = %s, want %s", reason.String(), got, reason)
+		}
+		if reason.Description() == "" {
+			t.Errorf("%s has no description", reason)
+		}
+	}
+}
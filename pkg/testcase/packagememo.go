@@ -0,0 +1,44 @@
+package testcase
+
+// Handles caching information derived from an entire package, which is needed for every file visited in the package.
+
+import (
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Caches a value computed by inspecting every file of a package, so it's only computed once per package instead of
+// once per visited file. Safe for concurrent use, since packages may be visited concurrently.
+// The cached values may refer to the package's syntax, so every cache is cleared by ReleaseCaches.
+type packageMemo[T any] struct {
+	mu     sync.Mutex
+	values map[*packages.Package]T
+}
+
+// Return the cached value for the package, computing and saving it using `compute` if it isn't cached yet.
+func (m *packageMemo[T]) get(pkg *packages.Package, compute func(*packages.Package) T) T {
+	m.mu.Lock()
+	value, ok := m.values[pkg]
+	m.mu.Unlock()
+	if ok {
+		return value
+	}
+
+	// Compute the value without holding the lock, so other packages aren't blocked
+	value = compute(pkg)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values == nil {
+		m.values = make(map[*packages.Package]T)
+	}
+	m.values[pkg] = value
+	return value
+}
+
+// Remove every cached value.
+func (m *packageMemo[T]) clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.values)
+}
@@ -3,12 +3,15 @@ package testcase
 // Implementations of various test case refactoring strategies based on their analysis results.
 
 import (
+	"context"
 	"fmt"
 	"go/ast"
 	"go/token"
 	"go/types"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sync"
 
 	"github.com/go-toolsmith/astcopy"
 	"github.com/maxgreen01/go-test-parser/pkg/asttools"
@@ -19,8 +22,9 @@ import (
 // If a refactoring is successfully generated, the test is executed using the original and refactored code.
 // The default behavior is to restore the original file contents after the refactoring is complete, but this
 // can be disabled by setting `keepRefactoredFiles` to true.
+// Test executions are stopped when `ctx` is cancelled, and the refactoring isn't applied if the original execution was interrupted.
 // Saves the result of the refactoring attempt to the AnalysisResult, and also returns a copy of the result.
-func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefactoredFiles bool) RefactorResult {
+func (ar *AnalysisResult) AttemptRefactoring(ctx context.Context, strategy RefactorStrategy, keepRefactoredFiles bool) RefactorResult {
 	if ar == nil {
 		slog.Error("Attempted to refactor a nil AnalysisResult", "strategy", strategy)
 		return RefactorResult{Strategy: strategy, GenerationStatus: RefactorGenerationStatusFail}
@@ -48,6 +52,18 @@ func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefa
 		return *rr
 	}
 
+	// Packages with errors can't be compiled, so the refactored test couldn't be executed
+	if !ar.TypeInfoComplete {
+		slog.Debug("Not refactoring TestCase because its package has errors", "testCase", tc, "strategy", strategy)
+		return *rr
+	}
+
+	// Refactoring strategies rely on `*testing.T` (or a suite), so other kinds of test functions (like benchmarks) aren't refactored
+	if tc.Kind != TestKindTest && tc.Kind != TestKindSuite {
+		slog.Debug("Not refactoring TestCase because it isn't a regular test", "testCase", tc, "kind", tc.Kind, "strategy", strategy)
+		return *rr
+	}
+
 	// Determine which refactoring strategy to apply
 	switch strategy {
 	case RefactorStrategySubtest:
@@ -57,6 +73,11 @@ func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefa
 			return *rr
 		}
 
+		// Refactoring modifies files and executes tests in the test's package directory, so only allow one
+		// refactoring at a time per directory in case multiple test cases are being analyzed concurrently
+		unlock := lockPackageDir(filepath.Dir(tc.FilePath))
+		defer unlock()
+
 		// Perform the actual refactoring
 		refactored, status, err := ar.refactorToSubtests()
 		if err != nil {
@@ -84,9 +105,13 @@ func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefa
 
 	// Execute the test case before saving the refactoring.
 	// This is run only after refactoring succeeds to avoid running tests unnecessarily (which is quite slow).
-	originalExecResult, err := tc.Execute()
+	originalExecResult, err := tc.Execute(ctx)
 	if err != nil {
-		if originalExecResult == TestExecutionResultFail {
+		if ctx.Err() != nil {
+			slog.Info("Test case execution interrupted before refactoring", "test", tc)
+			rr.OriginalExecutionResult = originalExecResult
+			return *rr
+		} else if originalExecResult == TestExecutionResultFail {
 			slog.Info("Test case execution failed normally before refactoring", "err", err, "test", tc)
 		} else {
 			slog.Error("Error executing test case before refactoring", "err", err, "test", tc)
@@ -110,28 +135,33 @@ func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefa
 		fileContents, err := os.ReadFile(filePath)
 		if err != nil {
 			slog.Error("Error reading original file contents", "err", err, "filePath", filePath, "test", tc)
+			restoreFiles(originalFileContents)
 			return *rr
 		}
 		originalFileContents[filePath] = fileContents
+		modifiedFiles.Store(filePath, fileContents)
 
 		// Update the file with the new AST data
 		if err := asttools.SaveFileContents(filePath, refactoring.File, fset); err != nil {
 			slog.Error("Error saving refactored file", "err", err, "filePath", filePath, "test", tc)
+			restoreFiles(originalFileContents)
 			return *rr
 		}
 	}
 
 	// Run the test after refactoring
-	refactoredExecResult, err := tc.Execute()
+	refactoredExecResult, err := tc.Execute(ctx)
 	if err != nil {
-		if refactoredExecResult == TestExecutionResultFail {
+		if ctx.Err() != nil {
+			slog.Info("Test case execution interrupted after refactoring", "test", tc)
+		} else if refactoredExecResult == TestExecutionResultFail {
 			slog.Info("Test case execution failed normally after refactoring", "err", err, "test", tc)
 		} else {
 			slog.Error("Error executing test case after refactoring", "err", err, "test", tc)
 		}
 	}
 	rr.RefactoredExecutionResult = refactoredExecResult
-	if rr.OriginalExecutionResult != rr.RefactoredExecutionResult {
+	if rr.OriginalExecutionResult != rr.RefactoredExecutionResult && ctx.Err() == nil {
 		slog.Warn("Refactored test case execution results do not match original results", "original", rr.OriginalExecutionResult, "refactored", rr.RefactoredExecutionResult, "test", tc)
 	}
 
@@ -144,6 +174,7 @@ func (ar *AnalysisResult) AttemptRefactoring(strategy RefactorStrategy, keepRefa
 				return *rr
 			}
 		}
+		modifiedFiles.Delete(refactoring.FilePath)
 
 		// Restore the original AST File data (and any dependents) to ensure that refactorings don't interfere with each other.
 		// Even if the file contents are retained on the disk, we need to revert the AST data to keep tests independent.
@@ -190,9 +221,10 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 	// the AST data it contains will be modified in-place during refactoring.
 	result := cloneHelperFunction(ss.Runner, ar)
 
-	// Detect the key/value variable names used by the loop (used to work with scenarios within the loop)
+	// Detect the key variable name used by the loop and the expression representing each scenario
+	// (used to work with scenarios within the loop)
 	var loopKeyName string
-	var loopValueName string
+	var scenarioExpr ast.Expr
 	switch loop := ss.Runner.(type) {
 	case *ast.RangeStmt:
 		if loop.Key == nil || loop.Value == nil {
@@ -200,9 +232,19 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 			return nil, RefactorGenerationStatusFail, nil
 		}
 		loopKeyName = loop.Key.(*ast.Ident).Name
-		loopValueName = loop.Value.(*ast.Ident).Name
+		scenarioExpr = ast.NewIdent(loop.Value.(*ast.Ident).Name) // e.g. `tt` in `for _, tt := range scenarios`
+
+	case *ast.ForStmt:
+		indexName, table := indexLoopTable(loop)
+		if table == nil {
+			slog.Warn("Cannot refactor test case with for loop that doesn't index a data structure", "test", tc)
+			return nil, RefactorGenerationStatusFail, nil
+		}
+		// Index the data structure directly, e.g. `tests[i]`, since any scenario variable declared in the loop body
+		// like `tt := tests[i]` is moved inside the subtest
+		loopKeyName = indexName
+		scenarioExpr = &ast.IndexExpr{X: astcopy.Expr(table), Index: ast.NewIdent(indexName)}
 
-	// todo LATER add support for `for-i` loops	(and modify assignment at end of func)
 	default:
 		slog.Warn("Cannot refactor test case with unsupported loop type", "type", fmt.Sprintf("%T", ss.Runner), "test", tc)
 		return nil, RefactorGenerationStatusFail, nil
@@ -223,7 +265,7 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 		return nil, RefactorGenerationStatusBadFields, nil
 	}
 
-	// Create an expression representing the the scenario name, e.g. `tt.Name``
+	// Create an expression representing the the scenario name, e.g. `tt.Name` or `tests[i].Name`
 	var scenarioNameExpr ast.Expr
 	if nameField == "map key" {
 		// Special case where map key is used -- name is the loop key
@@ -237,11 +279,7 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 		scenarioNameExpr = ast.NewIdent(loopKeyName)
 	} else {
 		// Regular case -- name is a scenario field
-
-		// Detect the name of the variable representing each scenario in the loop
-		scenarioVarName := loopValueName // e.g. `tt` in `for _, tt := range scenarios`
-
-		scenarioNameExpr = asttools.NewSelectorExpr(scenarioVarName, nameField)
+		scenarioNameExpr = &ast.SelectorExpr{X: scenarioExpr, Sel: ast.NewIdent(nameField)}
 	}
 
 	// Detect the name of the `*testing.T` parameter in the runner's function body, instead of hardcoding it to "t"
@@ -249,13 +287,40 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 	if funcDecl == nil || funcDecl.Type == nil {
 		return nil, RefactorGenerationStatusError, fmt.Errorf("cannot refactor test case with missing function declaration")
 	}
-	// Look for either `*testing.T` or `*require.TestingT`
-	tVarName, err := asttools.GetParamNameByType(funcDecl, &ast.StarExpr{X: asttools.NewSelectorExpr("testing", "T")}, &ast.StarExpr{X: asttools.NewSelectorExpr("require", "TestingT")})
+	// Look for either `*testing.T` or `*require.TestingT`, or use the receiver of suite methods, whose subtests are
+	// run using `s.Run(name, func() { ... })` instead
+	var tVarName string
+	var err error
+	suiteMethod := tc.Kind == TestKindSuite && funcDecl.Recv != nil
+	if suiteMethod {
+		if tVarName = receiverName(funcDecl); tVarName == "" {
+			err = fmt.Errorf("suite method %q has an unnamed receiver", funcDecl.Name.Name)
+		}
+	} else {
+		tVarName, err = asttools.GetParamNameByType(funcDecl, &ast.StarExpr{X: asttools.NewSelectorExpr("testing", "T")}, &ast.StarExpr{X: asttools.NewSelectorExpr("require", "TestingT")})
+	}
 	if err != nil {
-		slog.Warn("Cannot refactor test case because a `*testing.T` parameter was not detected", "function", funcDecl.Name.Name, "test", tc)
+		slog.Warn("Cannot refactor test case because a `*testing.T` parameter or suite receiver was not detected", "function", funcDecl.Name.Name, "test", tc)
 		return nil, RefactorGenerationStatusNoTester, nil
 	}
 
+	// The function literal for the subtest body takes the `*testing.T` parameter, or no parameters for suites
+	subtestParams := &ast.FieldList{
+		List: []*ast.Field{
+			{
+				Names: []*ast.Ident{
+					ast.NewIdent(tVarName),
+				},
+				Type: &ast.StarExpr{
+					X: asttools.NewSelectorExpr("testing", "T"),
+				},
+			},
+		},
+	}
+	if suiteMethod {
+		subtestParams = &ast.FieldList{}
+	}
+
 	// ENHANCEMENT
 	// To hopefully avoid compilation errors, try to replace `continue` runnerStatements in the loop body with `return` to make the test pass.
 	runnerStatements := ss.GetRunnerStatements()
@@ -289,19 +354,7 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 			// Function literal for the test body, of form `func(t *testing.T) { ... }`
 			&ast.FuncLit{
 				Type: &ast.FuncType{
-					// The `*testing.T` parameter
-					Params: &ast.FieldList{
-						List: []*ast.Field{
-							{
-								Names: []*ast.Ident{
-									ast.NewIdent(tVarName),
-								},
-								Type: &ast.StarExpr{
-									X: asttools.NewSelectorExpr("testing", "T"),
-								},
-							},
-						},
-					},
+					Params: subtestParams,
 				},
 				// The function body, populated with the original loop body statements
 				Body: &ast.BlockStmt{
@@ -319,6 +372,8 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 		if loopKeyName != loop.Key.(*ast.Ident).Name {
 			loop.Key.(*ast.Ident).Name = loopKeyName
 		}
+	case *ast.ForStmt:
+		loop.Body.List = []ast.Stmt{tRunCall}
 
 		// unsupported loop types are handled above
 	}
@@ -339,6 +394,49 @@ func (ar *AnalysisResult) refactorToSubtests() ([]RefactoredFunction, RefactorGe
 // ========== Helper Functions ==========
 //
 
+// Stores a mutex for each package directory where a refactoring has been performed, keyed by the directory path
+var packageDirLocks sync.Map
+
+// Stores the original contents of every file that is currently modified on the disk by an in-progress refactoring,
+// keyed by the file path. Used to restore the files if the program needs to exit before the refactoring finishes.
+var modifiedFiles sync.Map
+
+// Restores the original contents of the specified files after a refactoring fails partway through.
+func restoreFiles(originalFileContents map[string][]byte) {
+	for filePath, contents := range originalFileContents {
+		if err := os.WriteFile(filePath, contents, 0644); err != nil {
+			slog.Error("Error restoring original file contents", "err", err, "filePath", filePath)
+			continue
+		}
+		modifiedFiles.Delete(filePath)
+	}
+}
+
+// Restores the original contents of every file that is currently modified by an in-progress refactoring.
+// Intended to be called right before the program exits without waiting for refactorings to finish, e.g. when
+// the user interrupts the program a second time. Returns the number of files that were restored.
+func RestoreModifiedFiles() int {
+	restored := 0
+	modifiedFiles.Range(func(key, value any) bool {
+		filePath := key.(string)
+		if err := os.WriteFile(filePath, value.([]byte), 0644); err != nil {
+			slog.Error("Error restoring original file contents", "err", err, "filePath", filePath)
+			return true
+		}
+		modifiedFiles.Delete(filePath)
+		restored++
+		return true
+	})
+	return restored
+}
+
+// Locks the mutex corresponding to the specified package directory, returning a function that unlocks it.
+func lockPackageDir(dir string) (unlock func()) {
+	mu, _ := packageDirLocks.LoadOrStore(dir, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
 // If the provided statement is part of a helper function (i.e. not the test case function itself), this replaces
 // the surrounding helper function with a deep copy of itself in the included TestCase's AST file. It also updates
 // the AST references in the included ScenarioSet to match the new data. This returns a representation of the
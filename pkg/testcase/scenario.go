@@ -1,14 +1,205 @@
 package testcase
 
-import "go/ast"
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math"
+
+	"github.com/maxgreen01/go-test-parser/pkg/asttools"
+)
 
 // Represents an individual scenario defined by a table-driven test
-// todo LATER not implemented yet
 type Scenario struct {
-	Name string // the detected name of the scenario, either from a "name" or "desc" field, or the key of a map
+	// The name of the scenario, taken from the value of the name field, the map key, or the description of a Ginkgo
+	// entry. If the name can't be determined statically, it's based on the scenario's index like Go's unnamed subtests
+	// (e.g. "#01").
+	Name string
+
+	Index    int                       // the position of the scenario within the data structure that stores it
+	Expr     ast.Expr                  // the expression defining the scenario, e.g. a struct literal, a map entry, or an `Entry` call
+	Fields   map[string]*ScenarioField // the values of the scenario's fields, keyed by field name (omitted fields aren't included)
+	Position token.Position            // the source position of the scenario's definition
+}
+
+// Represents the value of a single field of a scenario
+type ScenarioField struct {
+	Expr  ast.Expr       // the expression assigned to the field
+	Value constant.Value // the constant value of the expression, or nil if it isn't constant
+}
+
+// The name of the field used to store the value of a map scenario whose values aren't structs, like `map[string]bool`
+const scenarioValueField = "value"
+
+// Decode the scenario defined by the expression, which is the element at the specified index of the data structure.
+// The scenario's name is resolved later by `ScenarioSet.Analyze()`, since it depends on the detected name field.
+func (ss *ScenarioSet) newScenario(index int, expr ast.Expr) Scenario {
+	tc := ss.TestCase
+	scenario := Scenario{
+		Index:  index,
+		Expr:   expr,
+		Fields: make(map[string]*ScenarioField),
+	}
+	if fset := tc.FileSet(); fset != nil {
+		scenario.Position = fset.Position(expr.Pos())
+	}
+
+	value := expr
+	switch ss.DataStructure {
+	case ScenarioMapDS:
+		if kvExpr, ok := expr.(*ast.KeyValueExpr); ok {
+			value = kvExpr.Value
+		}
+	case ScenarioGinkgoEntriesDS:
+		// The arguments after an entry's description are passed to the table's function, in order
+		if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) > 0 {
+			scenario.addPositionalFields(ss.fieldNames(), call.Args[1:], tc)
+		}
+		return scenario
+	}
+
+	// Struct scenarios may be defined using a pointer, like `&Scenario{...}`
+	if unary, ok := value.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+		value = unary.X
+	}
+	compositeLit, ok := value.(*ast.CompositeLit)
+	if _, isStruct := ss.ScenarioType.(*types.Struct); !ok || !isStruct {
+		// The scenario isn't a struct literal, so its value is stored as a single field
+		scenario.Fields[scenarioValueField] = newScenarioField(value, tc)
+		return scenario
+	}
+
+	if len(compositeLit.Elts) > 0 {
+		if _, keyed := compositeLit.Elts[0].(*ast.KeyValueExpr); keyed {
+			for _, elt := range compositeLit.Elts {
+				kvExpr, ok := elt.(*ast.KeyValueExpr)
+				if !ok {
+					continue
+				}
+				if key, ok := kvExpr.Key.(*ast.Ident); ok {
+					scenario.Fields[key.Name] = newScenarioField(kvExpr.Value, tc)
+				}
+			}
+			return scenario
+		}
+	}
+	scenario.addPositionalFields(ss.fieldNames(), compositeLit.Elts, tc)
+	return scenario
+}
+
+// Save the values of fields defined by position, e.g. in an unkeyed struct literal.
+// Values without a corresponding field name are ignored.
+func (s *Scenario) addPositionalFields(names []string, values []ast.Expr, tc *TestCase) {
+	for i, value := range values {
+		if i >= len(names) {
+			break
+		}
+		s.Fields[names[i]] = newScenarioField(value, tc)
+	}
+}
 
-	Expr ast.Expr
+// Create a ScenarioField for the expression, including its constant value if it has one.
+func newScenarioField(expr ast.Expr, tc *TestCase) *ScenarioField {
+	return &ScenarioField{Expr: expr, Value: tc.ConstantOf(expr)}
+}
+
+// Returns the names of the fields of the struct type used to define scenarios, in order.
+func (ss *ScenarioSet) fieldNames() []string {
+	var names []string
+	for field := range ss.GetFields() {
+		names = append(names, field.Name())
+	}
+	return names
+}
+
+// Returns the name of the scenario based on the detected name field, or a name based on the scenario's index
+// if the name isn't a constant string.
+func (ss *ScenarioSet) scenarioName(scenario *Scenario) string {
+	var nameExpr ast.Expr
+	switch ss.NameField {
+	case "":
+	case "map key":
+		if kvExpr, ok := scenario.Expr.(*ast.KeyValueExpr); ok {
+			nameExpr = kvExpr.Key
+		}
+	case "entry description":
+		if call, ok := scenario.Expr.(*ast.CallExpr); ok && len(call.Args) > 0 {
+			nameExpr = call.Args[0]
+		}
+	default:
+		if field := scenario.Fields[ss.NameField]; field != nil {
+			nameExpr = field.Expr
+		}
+	}
+
+	if nameExpr != nil {
+		if value := ss.TestCase.ConstantOf(nameExpr); value != nil && value.Kind() == constant.String {
+			if name := constant.StringVal(value); name != "" {
+				return name
+			}
+		}
+	}
+	return fmt.Sprintf("#%02d", scenario.Index)
+}
+
+//
+// =============== Output Methods ===============
+//
+
+// Helper struct for Marshaling JSON
+type scenarioJSON struct {
+	Name     string                       `json:"name"`
+	Index    int                          `json:"index"`
+	Position string                       `json:"position"`
+	Expr     string                       `json:"expr"`
+	Fields   map[string]scenarioFieldJSON `json:"fields"`
+}
+
+// Helper struct for Marshaling JSON
+type scenarioFieldJSON struct {
+	Expr  string `json:"expr"`
+	Value any    `json:"value"`
+}
+
+// Convert the scenario to its JSON representation, using the FileSet to print its expressions.
+func (s *Scenario) toJSON(fset *token.FileSet) scenarioJSON {
+	fields := make(map[string]scenarioFieldJSON, len(s.Fields))
+	for name, field := range s.Fields {
+		fields[name] = scenarioFieldJSON{
+			Expr:  asttools.NodeToString(field.Expr, fset),
+			Value: constantToJSON(field.Value),
+		}
+	}
+	return scenarioJSON{
+		Name:     s.Name,
+		Index:    s.Index,
+		Position: s.Position.String(),
+		Expr:     asttools.NodeToString(s.Expr, fset),
+		Fields:   fields,
+	}
+}
 
-	// todo maybe something like ScenarioDataStructure to type assert `Expr` more easily
-	// todo maybe store the Type of this Expr
+// Convert a constant value to the closest JSON value, i.e. a boolean, string, or number. Numbers that can't be
+// represented exactly are converted to their string representation instead. Returns nil if the value is nil.
+func constantToJSON(value constant.Value) any {
+	if value == nil {
+		return nil
+	}
+	switch value.Kind() {
+	case constant.Bool:
+		return constant.BoolVal(value)
+	case constant.String:
+		return constant.StringVal(value)
+	case constant.Int:
+		if i, exact := constant.Int64Val(value); exact {
+			return i
+		}
+	case constant.Float:
+		if f, exact := constant.Float64Val(value); exact && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	return value.ExactString()
 }
@@ -0,0 +1,83 @@
+package testcase
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestScenarios(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "scenarios")
+
+	// The expected scenarios of each test, in order. Fields map to the string representation of their constant values,
+	// or an empty string if the value isn't constant.
+	type scenario struct {
+		name   string
+		fields map[string]string
+	}
+	tests := []struct {
+		name      string
+		ds        ScenarioDataStructure
+		nameField string
+		scenarios []scenario
+	}{
+		{"TestKeyed", ScenarioStructListDS, "name", []scenario{
+			{"one", map[string]string{"name": `"one"`, "a": "1", "b": "1", "want": "1"}},
+			{"big", map[string]string{"name": `"big"`, "a": "1099511627776", "b": "2", "want": "0.5", "wantErr": "true"}},
+			{"#02", map[string]string{"name": "", "a": "1"}},
+		}},
+		{"TestPositional", ScenarioStructListDS, "", []scenario{
+			{"#00", map[string]string{"a": "1", "b": "2"}},
+			{"#01", map[string]string{"a": "-3", "b": "4"}},
+		}},
+		{"TestMap", ScenarioMapDS, "map key", []scenario{
+			{"yes", map[string]string{"value": "true"}},
+			{"no", map[string]string{"value": "false"}},
+		}},
+		{"TestMapOfStructs", ScenarioMapDS, "map key", []scenario{
+			{"empty", map[string]string{"in": `""`, "want": "0"}},
+			{"short", map[string]string{"in": `"ab"`, "want": "2"}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := analyzeFixtureFunc(t, funcs, tt.name)
+			ss := ar.ScenarioSet
+			if ss == nil || !ar.IsTableDriven() {
+				t.Fatal("test isn't table-driven")
+			}
+			if ss.DataStructure != tt.ds || ss.NameField != tt.nameField {
+				t.Errorf("ScenarioSet has DataStructure = %s and NameField = %q, want %s and %q", ss.DataStructure, ss.NameField, tt.ds, tt.nameField)
+			}
+			if len(ss.Scenarios) != len(tt.scenarios) {
+				t.Fatalf("found %d scenarios, want %d", len(ss.Scenarios), len(tt.scenarios))
+			}
+
+			for i, want := range tt.scenarios {
+				got := ss.Scenarios[i]
+				if got.Name != want.name || got.Index != i {
+					t.Errorf("scenario %d has name %q and index %d, want %q and %d", i, got.Name, got.Index, want.name, i)
+				}
+				if wantPos := ar.TestCase.FileSet().Position(got.Expr.Pos()); got.Position != wantPos || filepath.Base(got.Position.Filename) != "scenarios_test.go" {
+					t.Errorf("scenario %q has position %s, want %s", got.Name, got.Position, wantPos)
+				}
+				if len(got.Fields) != len(want.fields) {
+					t.Errorf("scenario %q has %d fields, want %d", got.Name, len(got.Fields), len(want.fields))
+				}
+				for name, wantValue := range want.fields {
+					field := got.Fields[name]
+					if field == nil {
+						t.Errorf("scenario %q has no field %q", got.Name, name)
+						continue
+					}
+					gotValue := ""
+					if field.Value != nil {
+						gotValue = field.Value.String()
+					}
+					if gotValue != wantValue {
+						t.Errorf("scenario %q has %s = %q, want %q", got.Name, name, gotValue, wantValue)
+					}
+				}
+			}
+		})
+	}
+}
@@ -22,7 +22,9 @@ type ScenarioSet struct {
 	ScenarioType types.Type // the definition of the `struct` type that individual scenarios are based on
 
 	DataStructure ScenarioDataStructure // describes the type of data structure used to store scenarios
-	Scenarios     []ast.Expr            // the individual scenarios themselves //todo LATER convert to type `[]Scenario`
+	Scenarios     []Scenario            // the individual scenarios themselves
+	Construction  ScenarioConstruction  // describes how the data structure storing the scenarios was built
+	Scope         ScenarioScope         // describes where the data structure storing the scenarios is declared
 
 	Runner ast.Stmt // the actual code that runs the subtest (which is expected to be either a `ForStmt` or a `RangeStmt`)
 
@@ -41,9 +43,10 @@ type ScenarioSet struct {
 type ScenarioDataStructure int
 
 const (
-	ScenarioNoDS         ScenarioDataStructure = iota // no table-driven test structure detected
-	ScenarioStructListDS                              // table-driven test using a slice or array of structs
-	ScenarioMapDS                                     // table-driven test using a map
+	ScenarioNoDS            ScenarioDataStructure = iota // no table-driven test structure detected
+	ScenarioStructListDS                                 // table-driven test using a slice or array of structs
+	ScenarioMapDS                                        // table-driven test using a map
+	ScenarioGinkgoEntriesDS                              // Ginkgo `DescribeTable` using `Entry` calls
 )
 
 func (sds ScenarioDataStructure) String() string {
@@ -52,6 +55,8 @@ func (sds ScenarioDataStructure) String() string {
 		return "structList"
 	case ScenarioMapDS:
 		return "map"
+	case ScenarioGinkgoEntriesDS:
+		return "ginkgoEntries"
 	default:
 		return "none"
 	}
@@ -71,12 +76,104 @@ func (sds *ScenarioDataStructure) UnmarshalJSON(data []byte) error {
 		*sds = ScenarioStructListDS
 	case "map":
 		*sds = ScenarioMapDS
+	case "ginkgoEntries":
+		*sds = ScenarioGinkgoEntriesDS
 	default:
 		*sds = ScenarioNoDS
 	}
 	return nil
 }
 
+// Represents how the data structure storing the scenarios was built
+type ScenarioConstruction int
+
+const (
+	ScenarioConstructionNone    ScenarioConstruction = iota // the scenarios were not found
+	ScenarioConstructionLiteral                             // a composite literal in the test function or a package-level variable
+	ScenarioConstructionAppend                              // `append` calls in the test function, e.g. `tests = append(tests, tt{...})` in a loop
+	ScenarioConstructionHelper                              // a helper function called by the test, e.g. `tests := buildTests()`
+)
+
+func (sc ScenarioConstruction) String() string {
+	switch sc {
+	case ScenarioConstructionLiteral:
+		return "literal"
+	case ScenarioConstructionAppend:
+		return "append"
+	case ScenarioConstructionHelper:
+		return "helper"
+	default:
+		return "none"
+	}
+}
+
+func (sc ScenarioConstruction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sc.String())
+}
+
+func (sc *ScenarioConstruction) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	switch str {
+	case "literal":
+		*sc = ScenarioConstructionLiteral
+	case "append":
+		*sc = ScenarioConstructionAppend
+	case "helper":
+		*sc = ScenarioConstructionHelper
+	default:
+		*sc = ScenarioConstructionNone
+	}
+	return nil
+}
+
+// Represents where the data structure storing the scenarios is declared, relative to the test function
+type ScenarioScope int
+
+const (
+	ScenarioScopeNone    ScenarioScope = iota // the scenarios were not found
+	ScenarioScopeLocal                        // inside a function, i.e. the test itself or a helper function it calls
+	ScenarioScopeFile                         // a package-level variable declared in the same file as the test
+	ScenarioScopePackage                      // a package-level variable declared in another file, possibly in the package under test
+)
+
+func (sc ScenarioScope) String() string {
+	switch sc {
+	case ScenarioScopeLocal:
+		return "local"
+	case ScenarioScopeFile:
+		return "file"
+	case ScenarioScopePackage:
+		return "package"
+	default:
+		return "none"
+	}
+}
+
+func (sc ScenarioScope) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sc.String())
+}
+
+func (sc *ScenarioScope) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	switch str {
+	case "local":
+		*sc = ScenarioScopeLocal
+	case "file":
+		*sc = ScenarioScopeFile
+	case "package":
+		*sc = ScenarioScopePackage
+	default:
+		*sc = ScenarioScopeNone
+	}
+	return nil
+}
+
 //
 // =============== Analysis Methods ===============
 //
@@ -86,7 +183,16 @@ func (ss *ScenarioSet) Analyze() {
 	ss.NameField = ss.detectNameField()
 	ss.ExpectedFields = ss.detectExpectedFields()
 	ss.HasFunctionFields = ss.detectFunctionFields()
-	ss.UsesSubtest, _ = ss.detectSubtest()
+	if ss.DataStructure == ScenarioGinkgoEntriesDS {
+		ss.UsesSubtest = true // Ginkgo runs each entry as a separate spec
+	} else {
+		ss.UsesSubtest, _ = ss.detectSubtest()
+	}
+
+	// Resolve the name of each scenario now that the name field is known
+	for i := range ss.Scenarios {
+		ss.Scenarios[i].Name = ss.scenarioName(&ss.Scenarios[i])
+	}
 
 	// todo LATER consider expanding the statements inside the runner loop, just like with TestCase statements
 	//     since TestCase already expands all statements, we can probably store a copy of the corresponding statement without recomputing
@@ -96,8 +202,8 @@ func (ss *ScenarioSet) Analyze() {
 // Returns the name of the field representing the name of each scenario
 func (ss *ScenarioSet) detectNameField() string {
 	// In the special case for map data structures where the key represents the scenario name,
-	// the name field would already be set by `DetectScenarioDataStructure()`
-	if ss.DataStructure == ScenarioMapDS && ss.NameField != "" {
+	// the name field would already be set by `DetectScenarioDataStructure()`, and likewise for Ginkgo entries
+	if (ss.DataStructure == ScenarioMapDS || ss.DataStructure == ScenarioGinkgoEntriesDS) && ss.NameField != "" {
 		return ss.NameField
 	}
 
@@ -173,8 +279,12 @@ func (ss *ScenarioSet) detectFunctionFields() bool {
 
 // Returns a bool indicating whether `t.Run()` is called inside the loop body, as well as a reference to the `t.Run()` statement
 func (ss *ScenarioSet) detectSubtest() (bool, *ast.CallExpr) {
-	// Detect the name of the `testing.T` parameter instead of hardcoding "t"
-	tVarName, err := asttools.GetParamNameByType(ss.TestCase.funcDecl, &ast.StarExpr{X: asttools.NewSelectorExpr("testing", "T")})
+	if ss.TestCase.Kind == TestKindGinkgo {
+		return false, nil // Ginkgo specs are nested using containers instead
+	}
+
+	// Detect the name of the `testing.T` parameter instead of hardcoding "t", or the receiver of suite methods
+	tVarName, err := ss.TestCase.testerName()
 	if err != nil {
 		slog.Warn("Cannot detect `*testing.T` parameter in test case", "err", err, "test", ss.TestCase)
 		return false, nil
@@ -253,7 +363,9 @@ type scenarioSetJSON struct {
 	ScenarioType string `json:"scenarioType"`
 
 	DataStructure ScenarioDataStructure `json:"dataStructure"`
-	Scenarios     []string              `json:"scenarios"`
+	Scenarios     []scenarioJSON        `json:"scenarios"`
+	Construction  ScenarioConstruction  `json:"construction"`
+	Scope         ScenarioScope         `json:"scope"`
 
 	Runner string `json:"runner"`
 
@@ -277,18 +389,19 @@ func (ss *ScenarioSet) MarshalJSON() ([]byte, error) {
 	}
 
 	// Marshal individual Scenario data
-	// todo LATER remove when implement Marshal in Scenario
 	fset := ss.TestCase.FileSet()
-	scenarioStrs := make([]string, len(ss.Scenarios))
-	for i, node := range ss.Scenarios {
-		scenarioStrs[i] = asttools.NodeToString(node, fset)
+	scenarios := make([]scenarioJSON, len(ss.Scenarios))
+	for i := range ss.Scenarios {
+		scenarios[i] = ss.Scenarios[i].toJSON(fset)
 	}
 
 	return json.Marshal(scenarioSetJSON{
 		ScenarioType: scenarioTypeStr,
 
 		DataStructure: ss.DataStructure,
-		Scenarios:     scenarioStrs,
+		Scenarios:     scenarios,
+		Construction:  ss.Construction,
+		Scope:         ss.Scope,
 
 		Runner: asttools.NodeToString(ss.Runner, fset),
 
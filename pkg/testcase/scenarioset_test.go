@@ -0,0 +1,44 @@
+package testcase
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestScenarioConstruction(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "construction")
+
+	tests := []struct {
+		name         string
+		construction ScenarioConstruction
+		scenarios    []string
+	}{
+		{"TestHelperReturn", ScenarioConstructionHelper, []string{"two", "three"}},
+		{"TestHelperAssign", ScenarioConstructionHelper, []string{"one"}},
+		{"TestAppendHelper", ScenarioConstructionHelper, []string{"h"}},
+		// Scenarios appended in a loop are only counted once
+		{"TestAppendLoop", ScenarioConstructionAppend, []string{"#00", "ten", "eleven"}},
+		{"TestLiteralPlusAppend", ScenarioConstructionAppend, []string{"one", "two"}},
+		// Appending to a different slice doesn't add scenarios
+		{"TestAppendResults", ScenarioConstructionLiteral, []string{"one", "two"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := analyzeFixtureFunc(t, funcs, tt.name)
+			ss := ar.ScenarioSet
+			if ss == nil || !ar.IsTableDriven() {
+				t.Fatal("test isn't table-driven")
+			}
+			if ss.Construction != tt.construction || ss.Scope != ScenarioScopeLocal {
+				t.Errorf("ScenarioSet has Construction = %s and Scope = %s, want %s and local", ss.Construction, ss.Scope, tt.construction)
+			}
+			var names []string
+			for _, scenario := range ss.Scenarios {
+				names = append(names, scenario.Name)
+			}
+			if !slices.Equal(names, tt.scenarios) {
+				t.Errorf("found scenarios %q, want %q", names, tt.scenarios)
+			}
+		})
+	}
+}
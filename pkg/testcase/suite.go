@@ -0,0 +1,258 @@
+package testcase
+
+// Handles detecting the test methods of testify suites, which are run by a regular test function using `suite.Run`.
+
+import (
+	"go/ast"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/maxgreen01/go-test-parser/pkg/asttools"
+	"golang.org/x/tools/go/packages"
+)
+
+// The import path of the testify suite package
+const testifySuitePath = "github.com/stretchr/testify/suite"
+
+// Represents a testify suite, i.e. a struct type embedding `suite.Suite`, whose methods named like `TestXxx` are each
+// run as a separate test by a regular test function that passes an instance of the suite to `suite.Run`.
+type Suite struct {
+	Name   string // the name of the suite type
+	Runner string // the name of the test function that runs the suite, or empty if none was found
+}
+
+// Memoization cache for findPackageSuites, since the suites are the same for every file in a package
+var suiteMemo packageMemo[map[string]*Suite]
+
+// Find the testify suites defined in the package whose test methods may be declared in the specified file,
+// keyed by the name of the suite type. Returns nil without inspecting the rest of the package if the file
+// doesn't declare any methods that could be suite tests. The rest of the package is only inspected once,
+// so the returned map is shared by every file in the package and must not be modified.
+//
+// Suite types are detected using type information when it's available (i.e. types whose method set includes
+// the methods of `suite.TestingSuite`), and otherwise using the syntax of their definitions.
+func FindSuites(file *ast.File, pkg *packages.Package) map[string]*Suite {
+	if file == nil || pkg == nil || !hasTestMethods(file) {
+		return nil
+	}
+	return suiteMemo.get(pkg, findPackageSuites)
+}
+
+// Find every testify suite defined in the package and the test functions that run them, keyed by the name of the
+// suite type. Returns nil if the package doesn't define any suites.
+func findPackageSuites(pkg *packages.Package) map[string]*Suite {
+	// Find every struct type that embeds `suite.Suite`, either directly or through another suite type in the package
+	suites := make(map[string]*Suite)
+	embeds := make(map[string][]string) // maps the names of struct types to the names of local types they embed
+	for _, f := range pkg.Syntax {
+		suiteName := importName(f, testifySuitePath)
+		for _, decl := range f.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				name := typeSpec.Name.Name
+				if pkg.TypesInfo != nil && isSuiteType(pkg.TypesInfo.Defs[typeSpec.Name]) {
+					suites[name] = &Suite{Name: name}
+					continue
+				}
+				for _, field := range structType.Fields.List {
+					if len(field.Names) > 0 {
+						continue // Not an embedded field
+					}
+					fieldType := field.Type
+					if star, ok := fieldType.(*ast.StarExpr); ok {
+						fieldType = star.X
+					}
+					if suiteName != "" && asttools.MatchSelectorExpr(fieldType, suiteName, "Suite") {
+						suites[name] = &Suite{Name: name}
+					} else if ident, ok := fieldType.(*ast.Ident); ok {
+						embeds[name] = append(embeds[name], ident.Name)
+					}
+				}
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, embedded := range embeds {
+			if suites[name] != nil {
+				continue
+			}
+			for _, e := range embedded {
+				if suites[e] != nil {
+					suites[name] = &Suite{Name: name}
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	if len(suites) == 0 {
+		return nil
+	}
+
+	// Find the test functions that run each suite, i.e. those that call `suite.Run(t, suiteInstance)`
+	for _, f := range pkg.Syntax {
+		suiteName := importName(f, testifySuitePath)
+		if suiteName == "" {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			if kind, valid, _ := ClassifyFunction(fn); !valid || kind != TestKindTest {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) != 2 || !asttools.MatchSelectorExpr(call.Fun, suiteName, "Run") {
+					return true
+				}
+				if s := suites[suiteInstanceType(call.Args[1], pkg.TypesInfo)]; s != nil && s.Runner == "" {
+					s.Runner = fn.Name.Name
+				}
+				return true
+			})
+		}
+	}
+	return suites
+}
+
+// Return the suite that the function is a test method of, or nil if it isn't a suite test method.
+// Suite test methods have a name starting with "Test" (which may be followed by any character), and no parameters,
+// type parameters, or return values.
+func SuiteOf(funcDecl *ast.FuncDecl, suites map[string]*Suite) *Suite {
+	if len(suites) == 0 || funcDecl == nil || !isTestMethod(funcDecl) {
+		return nil
+	}
+	funcType := funcDecl.Type
+	if funcType.TypeParams != nil || funcType.Results != nil || len(funcType.Params.List) != 0 {
+		return nil
+	}
+	return suites[receiverTypeName(funcDecl)]
+}
+
+// Return whether the file declares any methods named like `TestXxx`.
+func hasTestMethods(file *ast.File) bool {
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && isTestMethod(fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// Return whether the function is a method named like `TestXxx`.
+func isTestMethod(funcDecl *ast.FuncDecl) bool {
+	return funcDecl.Recv != nil && len(funcDecl.Recv.List) == 1 && strings.HasPrefix(funcDecl.Name.Name, "Test")
+}
+
+// Return the name of the type of a method's receiver, ignoring pointers and type parameters.
+func receiverTypeName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+	return localTypeName(funcDecl.Recv.List[0].Type)
+}
+
+// Return the name of a method's receiver, or an empty string if it's unnamed.
+func receiverName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 || len(funcDecl.Recv.List[0].Names) == 0 {
+		return ""
+	}
+	if name := funcDecl.Recv.List[0].Names[0].Name; name != "_" {
+		return name
+	}
+	return ""
+}
+
+// Return the name of the local type referenced by the expression (e.g. `Foo` for `*Foo` or `Foo[T]`),
+// or an empty string if it isn't a local type.
+func localTypeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return localTypeName(t.X)
+	case *ast.IndexExpr:
+		return localTypeName(t.X)
+	case *ast.IndexListExpr:
+		return localTypeName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// Return the name of the local type of the suite instance passed to `suite.Run`, using type information if it's
+// available, and otherwise the syntax of expressions like `new(MySuite)` or `&MySuite{}`.
+func suiteInstanceType(expr ast.Expr, info *types.Info) string {
+	if info != nil {
+		if t := info.TypeOf(expr); t != nil {
+			if named, ok := asttools.Unpointer(t).(*types.Named); ok {
+				return named.Obj().Name()
+			}
+		}
+	}
+
+	switch x := expr.(type) {
+	case *ast.UnaryExpr:
+		return suiteInstanceType(x.X, nil)
+	case *ast.CompositeLit:
+		return localTypeName(x.Type)
+	case *ast.CallExpr:
+		if ident, ok := x.Fun.(*ast.Ident); ok && ident.Name == "new" && len(x.Args) == 1 {
+			return localTypeName(x.Args[0])
+		}
+	}
+	return ""
+}
+
+// Return whether the object is a type whose pointer type implements the `suite.TestingSuite` interface,
+// which is the case for every struct embedding `suite.Suite`.
+func isSuiteType(obj types.Object) bool {
+	typeName, ok := obj.(*types.TypeName)
+	if !ok {
+		return false
+	}
+	methods := types.NewMethodSet(types.NewPointer(typeName.Type()))
+	for _, name := range []string{"T", "SetT", "SetS"} {
+		sel := methods.Lookup(nil, name)
+		if sel == nil {
+			return false
+		}
+		if fn, ok := sel.Obj().(*types.Func); !ok || fn.Pkg() == nil || fn.Pkg().Path() != testifySuitePath {
+			return false
+		}
+	}
+	return true
+}
+
+// Return the name that the file uses to refer to the imported package with the specified path,
+// or an empty string if the package isn't imported (or is imported using a blank or dot import).
+func importName(file *ast.File, path string) string {
+	for _, spec := range file.Imports {
+		if p, err := strconv.Unquote(spec.Path.Value); err != nil || p != path {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == "_" || spec.Name.Name == "." {
+				return ""
+			}
+			return spec.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package testcase
+
+import "testing"
+
+func TestFindSuites(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "suites")
+
+	tests := []struct {
+		name   string
+		suite  string // empty if the function isn't a suite test method
+		runner string
+	}{
+		{"CalcSuite.TestAdd", "CalcSuite", "TestCalcSuite"},
+		{"CalcSuite.TestValueReceiver", "CalcSuite", "TestCalcSuite"},
+		{"CalcSuite.TestOtherFile", "CalcSuite", "TestCalcSuite"},
+		{"CalcSuite.TestWithParam", "", ""},
+		{"CalcSuite.TestWithResult", "", ""},
+		{"CalcSuite.helper", "", ""},
+		{"EmbeddedSuite.TestEmbedded", "EmbeddedSuite", "TestEmbeddedSuite"},
+		{"OrphanSuite.TestOrphan", "OrphanSuite", ""},
+		{"AliasSuite.TestAlias", "AliasSuite", "TestAliasSuite"},
+		{"NotSuite.TestNotSuite", "", ""},
+		{"TestCalcSuite", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := findFixtureFunc(t, funcs, tt.name)
+			suite := SuiteOf(fn.decl, FindSuites(fn.file, fn.pkg))
+			if tt.suite == "" {
+				if suite != nil {
+					t.Errorf("SuiteOf() = %+v, want nil", *suite)
+				}
+				return
+			}
+			if suite == nil {
+				t.Fatalf("SuiteOf() = nil, want suite %q", tt.suite)
+			}
+			if suite.Name != tt.suite || suite.Runner != tt.runner {
+				t.Errorf("SuiteOf() = %+v, want {Name:%s Runner:%s}", *suite, tt.suite, tt.runner)
+			}
+
+			tc := CreateSuiteTestCase(fn.decl, fn.file, fn.pkg, "suites", suite)
+			if tc.Kind != TestKindSuite || tc.Suite != tt.suite || tc.SuiteRunner != tt.runner {
+				t.Errorf("CreateSuiteTestCase() = {Kind:%s Suite:%s SuiteRunner:%s}, want {Kind:suite Suite:%s SuiteRunner:%s}", tc.Kind, tc.Suite, tc.SuiteRunner, tt.suite, tt.runner)
+			}
+		})
+	}
+}
+
+func TestFindSuitesWithoutTestMethods(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "suites")
+
+	// Files without test methods don't need the package's suites, even if the package defines some
+	fn := findFixtureFunc(t, funcs, "Add")
+	if suites := FindSuites(fn.file, fn.pkg); suites != nil {
+		t.Errorf("FindSuites() = %v, want nil", suites)
+	}
+}
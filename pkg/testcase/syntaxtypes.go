@@ -0,0 +1,225 @@
+package testcase
+
+// Provides fallbacks for approximating types using only syntax, for use when type information is incomplete
+// (e.g. because the test's package has errors).
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"log/slog"
+
+	"github.com/maxgreen01/go-test-parser/pkg/asttools"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Maximum depth when resolving named types and variable definitions from syntax, which avoids infinite recursion
+// when types refer to themselves
+const maxSyntaxTypeDepth = 8
+
+// Return the type of the expression from the type information if it's available and valid.
+// Otherwise, approximates the type using the syntax that defines the expression, or returns nil if this isn't possible.
+func (tc *TestCase) typeOfWithFallback(expr ast.Expr) types.Type {
+	if typ := tc.TypeOf(expr); isValidType(typ) {
+		return typ
+	}
+	typ := tc.syntaxTypeOf(expr, 0)
+	if typ != nil {
+		slog.Debug("Approximated missing type information using syntax", "type", typ, "testCase", tc)
+	}
+	return typ
+}
+
+// Return whether the type is known, i.e. it's not nil and the type checker was able to determine it.
+func isValidType(typ types.Type) bool {
+	return typ != nil && typ != types.Typ[types.Invalid]
+}
+
+// Approximate the type of a value expression using only syntax, by finding the composite literal or declaration
+// that defines it. Returns nil if the type can't be determined.
+func (tc *TestCase) syntaxTypeOf(expr ast.Expr, depth int) types.Type {
+	if expr == nil || depth > maxSyntaxTypeDepth {
+		return nil
+	}
+
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return tc.syntaxTypeOf(x.X, depth+1)
+
+	case *ast.CompositeLit:
+		if x.Type == nil {
+			return nil // The type is elided, so it depends on the enclosing literal
+		}
+		return tc.syntaxTypeOfTypeExpr(x.Type, depth+1)
+
+	case *ast.UnaryExpr:
+		if x.Op == token.AND {
+			if elem := tc.syntaxTypeOf(x.X, depth+1); elem != nil {
+				return types.NewPointer(elem)
+			}
+		}
+		return nil
+
+	case *ast.Ident:
+		// Find the statement or declaration that defines the variable
+		obj := tc.ObjectOf(x)
+		if obj == nil || !obj.Pos().IsValid() {
+			return nil
+		}
+		file := asttools.GetEnclosingFile(obj.Pos(), tc.GetPackageFiles())
+		if file == nil {
+			return nil
+		}
+		path, _ := astutil.PathEnclosingInterval(file, obj.Pos(), obj.Pos())
+		for _, node := range path {
+			switch def := node.(type) {
+			case *ast.ValueSpec:
+				if def.Type != nil {
+					return tc.syntaxTypeOfTypeExpr(def.Type, depth+1)
+				}
+				for i, name := range def.Names {
+					if name.Pos() == obj.Pos() && i < len(def.Values) && len(def.Names) == len(def.Values) {
+						return tc.syntaxTypeOf(def.Values[i], depth+1)
+					}
+				}
+				return nil
+			case *ast.AssignStmt:
+				for i, lhs := range def.Lhs {
+					if lhs.Pos() == obj.Pos() && len(def.Lhs) == len(def.Rhs) {
+						return tc.syntaxTypeOf(def.Rhs[i], depth+1)
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Convert a type expression (e.g. `[]struct{ name string }`) to a type using only syntax.
+// Named types are resolved using the type information if it's valid, or otherwise by finding their declarations.
+// Types that can't be determined (e.g. types from packages that failed to load) are represented as invalid types.
+func (tc *TestCase) syntaxTypeOfTypeExpr(expr ast.Expr, depth int) types.Type {
+	if expr == nil || depth > maxSyntaxTypeDepth {
+		return types.Typ[types.Invalid]
+	}
+
+	switch x := expr.(type) {
+	case *ast.ParenExpr:
+		return tc.syntaxTypeOfTypeExpr(x.X, depth+1)
+
+	case *ast.ArrayType:
+		elem := tc.syntaxTypeOfTypeExpr(x.Elt, depth+1)
+		if x.Len == nil {
+			return types.NewSlice(elem)
+		}
+		// The array length doesn't matter for the analysis, so it isn't evaluated
+		return types.NewArray(elem, -1)
+
+	case *ast.MapType:
+		return types.NewMap(tc.syntaxTypeOfTypeExpr(x.Key, depth+1), tc.syntaxTypeOfTypeExpr(x.Value, depth+1))
+
+	case *ast.StarExpr:
+		return types.NewPointer(tc.syntaxTypeOfTypeExpr(x.X, depth+1))
+
+	case *ast.FuncType:
+		// Parameters are omitted since only the fact that this is a function type matters for the analysis
+		return types.NewSignatureType(nil, nil, nil, nil, nil, false)
+
+	case *ast.StructType:
+		var fields []*types.Var
+		var tags []string
+		for _, field := range x.Fields.List {
+			typ := tc.syntaxTypeOfTypeExpr(field.Type, depth+1)
+			tag := ""
+			if field.Tag != nil {
+				tag = field.Tag.Value
+			}
+			if len(field.Names) == 0 {
+				// Embedded field, which is named after its type
+				name := embeddedFieldName(field.Type)
+				fields = append(fields, types.NewField(field.Pos(), tc.typesPackage(), name, typ, true))
+				tags = append(tags, tag)
+				continue
+			}
+			for _, name := range field.Names {
+				fields = append(fields, types.NewField(name.Pos(), tc.typesPackage(), name.Name, typ, false))
+				tags = append(tags, tag)
+			}
+		}
+		return types.NewStruct(fields, tags)
+
+	case *ast.Ident, *ast.SelectorExpr:
+		// Use the type information for named types whenever it's available
+		if typ := tc.TypeOf(x); isValidType(typ) {
+			return typ
+		}
+		ident, ok := x.(*ast.Ident)
+		if !ok {
+			return types.Typ[types.Invalid] // Type from another package that couldn't be loaded
+		}
+		obj := tc.ObjectOf(ident)
+		if obj == nil {
+			// Unresolved identifiers may still be predeclared types like `string` or `error`
+			if typeName, ok := types.Universe.Lookup(ident.Name).(*types.TypeName); ok {
+				return typeName.Type()
+			}
+			return types.Typ[types.Invalid]
+		}
+		if obj.Parent() == types.Universe {
+			return obj.Type()
+		}
+		// Type declared in the same package, so convert its declaration instead
+		if file := asttools.GetEnclosingFile(obj.Pos(), tc.GetPackageFiles()); file != nil {
+			path, _ := astutil.PathEnclosingInterval(file, obj.Pos(), obj.Pos())
+			for _, node := range path {
+				if spec, ok := node.(*ast.TypeSpec); ok {
+					return tc.syntaxTypeOfTypeExpr(spec.Type, depth+1)
+				}
+			}
+		}
+	}
+	return types.Typ[types.Invalid]
+}
+
+// Return the type of the elements of a slice or array type, or the values of a map type, or nil for other types.
+// Used to find the type of elements in a composite literal whose own types were elided.
+func syntaxElementType(typ types.Type) types.Type {
+	if typ == nil {
+		return nil
+	}
+	switch x := typ.Underlying().(type) {
+	case *types.Slice:
+		return x.Elem()
+	case *types.Array:
+		return x.Elem()
+	case *types.Map:
+		return x.Elem()
+	}
+	return nil
+}
+
+// Return the name of an embedded struct field, which is the name of its type without any package qualifier or pointer.
+func embeddedFieldName(typeExpr ast.Expr) string {
+	switch x := typeExpr.(type) {
+	case *ast.StarExpr:
+		return embeddedFieldName(x.X)
+	case *ast.SelectorExpr:
+		return x.Sel.Name
+	case *ast.Ident:
+		return x.Name
+	case *ast.IndexExpr:
+		return embeddedFieldName(x.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(x.X)
+	}
+	return "_"
+}
+
+// Return the type-checked package of the test case, or nil if it's unavailable.
+func (tc *TestCase) typesPackage() *types.Package {
+	if tc.pkgInfo == nil {
+		return nil
+	}
+	return tc.pkgInfo.Types
+}
@@ -4,15 +4,18 @@ package testcase
 // The fields of the structs defined in this package should never be modified directly.
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"log/slog"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/maxgreen01/go-test-parser/pkg/asttools"
 	"golang.org/x/tools/go/packages"
@@ -21,13 +24,29 @@ import (
 // Represents an individual test case defined at the top level of a Go source file.
 type TestCase struct {
 	// High-level identifiers
-	TestName    string // the name of the test case itself
-	PackageName string // the name of the package where the test case is defined, as it appears in the source code
-	FilePath    string // the path to the file where the test case is defined
-	ProjectName string // the name of the overarching project that the test case is part of
+	TestName       string   // the name of the test case itself
+	PackageName    string   // the name of the package where the test case is defined, as it appears in the source code
+	PackageVariant string   // the ID of the loaded package variant the test case was extracted from, e.g. "pkg [pkg.test]"
+	FilePath       string   // the path to the file where the test case is defined
+	ProjectName    string   // the name of the overarching project that the test case is part of
+	Kind           TestKind // the kind of test function, e.g. a regular test or a benchmark
+
+	// The build constraint that must be satisfied for the test case to be built, e.g. "integration && linux",
+	// combining the file's `//go:build` line and the platform implied by its name. Empty if it's always built.
+	BuildConstraint string
+
+	// For testify suite methods, the name of the suite type and the test function that runs the suite (see Suite).
+	// For Ginkgo specs, the description passed to `RunSpecs` and the test function that calls it.
+	// Both are empty for other kinds of tests, and the runner is also empty if it wasn't found.
+	Suite       string
+	SuiteRunner string
+
+	// For Ginkgo specs, the texts of the spec's containers and the spec itself, starting from the root of the spec tree
+	SpecPath []string
 
 	// Raw syntax data
-	funcDecl *ast.FuncDecl     // the AST definition of the test case function itself
+	funcDecl *ast.FuncDecl     // the AST definition of the test case function itself (or the function passed to a Ginkgo spec)
+	spec     *GinkgoNode       // the node in the Ginkgo spec tree that defines the test case, if it's a Ginkgo spec
 	file     *ast.File         // the AST file where the test case is defined
 	pkgInfo  *packages.Package // the actual AST information about the test's package, including AST data, types, etc.
 }
@@ -40,12 +59,23 @@ func CreateTestCase(funcDecl *ast.FuncDecl, file *ast.File, pkg *packages.Packag
 		return TestCase{}
 	}
 
+	filePath := pkg.Fset.Position(file.FileStart).Filename
+	kind, _, _ := ClassifyFunction(funcDecl)
+	buildConstraint := ""
+	if expr := fileBuildConstraint(file, filePath); expr != nil {
+		buildConstraint = expr.String()
+	}
+
 	// Create the TestCase itself
 	return TestCase{
-		TestName:    funcDecl.Name.Name,
-		PackageName: file.Name.Name, // todo CLEANUP this should probably be pkg.PkgPath for extra precision
-		FilePath:    pkg.Fset.Position(file.FileStart).Filename,
-		ProjectName: project,
+		TestName:       funcDecl.Name.Name,
+		PackageName:    file.Name.Name, // todo CLEANUP this should probably be pkg.PkgPath for extra precision
+		PackageVariant: pkg.ID,
+		FilePath:       filePath,
+		ProjectName:    project,
+		Kind:           kind,
+
+		BuildConstraint: buildConstraint,
 
 		funcDecl: funcDecl,
 		file:     file,
@@ -53,79 +83,27 @@ func CreateTestCase(funcDecl *ast.FuncDecl, file *ast.File, pkg *packages.Packag
 	}
 }
 
-// Determine if the given function declaration is a valid test case.
+// Create a new TestCase for a method of a testify suite, which is run by the suite's runner.
+func CreateSuiteTestCase(funcDecl *ast.FuncDecl, file *ast.File, pkg *packages.Package, project string, suite *Suite) TestCase {
+	tc := CreateTestCase(funcDecl, file, pkg, project)
+	if suite != nil {
+		tc.Kind = TestKindSuite
+		tc.Suite = suite.Name
+		tc.SuiteRunner = suite.Runner
+	}
+	return tc
+}
+
+// Determine if the given function declaration is a valid regular test case, like `func TestXxx(t *testing.T)`.
 // Returns two booleans: `valid` indicating whether this is a valid test case, and
 // `badFormat` indicating whether the test case has an incorrect (but acceptable) format.
 // `badFormat` is false if the function is not valid.
-//
-// The test case is validated using the following criteria:
-// - The function name starts with "Test" followed by a capital letter
-// - The function has `*testing.T` as its only formal parameter
-// - The function does not have any receiver (i.e., it is not a method)
-// - The function does not have any generic type parameters
-// - The function does not return any values
+// See ClassifyFunction for recognizing the other kinds of test functions, like benchmarks and examples.
 func IsValidTestCase(funcDecl *ast.FuncDecl) (valid bool, badFormat bool) {
-	if funcDecl == nil || funcDecl.Name == nil {
-		return false, false
-	}
-	name := funcDecl.Name.Name
-
-	// make sure the function name starts with "Test"
-	// todo MAYBE allow this (and condition below) to accept "Fuzz" or "Benchmark" and indicate a different category somehow (maybe using enum `type` in TestCase)
-	if !strings.HasPrefix(name, "Test") {
-		// slog.Debug("\tfunction name does not start with 'Test'", "name", name)
-		return false, false
-	}
-
-	// the function's 5th letter *should* be capitalized, but it's not strictly required
-	if len(name) < 5 || (name[4] < 'A' || name[4] > 'Z') {
-		// slog.Debug("\tfunction has bad format", "name", name)
-		badFormat = true
-	}
-
-	funcType := funcDecl.Type
-
-	// make sure the function has no receiver, type parameters, or return value
-	if funcDecl.Recv != nil || funcType.TypeParams != nil || funcType.Results != nil {
-		// todo maaaaaaaaybe allow this case with badFormat? print out how many times this case occurs and see if it's worth supporting
-		// slog.Debug("\tfunction has bad signature", "name", name)
-		return false, false
-	}
-
-	// make sure the function has exactly one parameter
-	if len(funcType.Params.List) != 1 {
-		// slog.Debug("\tfunction has wrong param count", "name", name)
-		return false, false
-	}
-	paramType := funcType.Params.List[0].Type
-
-	// safely extract all components of the parameter type, expecting something like `*testing.T`
-	starExpr, ok := paramType.(*ast.StarExpr)
-	if !ok {
-		// slog.Debug("\tfunction has non-pointer param type", "name", name, "paramType", reflect.TypeOf(paramType))
-		return false, false
-	}
-	selectorExpr, ok := starExpr.X.(*ast.SelectorExpr)
-	if !ok {
-		// slog.Debug("\tfunction has non-selector param type", "name", name, "paramType", reflect.TypeOf(starExpr.X))
-		return false, false
-	}
-	paramPackageIdent, ok := selectorExpr.X.(*ast.Ident)
-	if !ok {
-		// slog.Debug("\tfunction has non-ident param package", "name", name, "paramType", reflect.TypeOf(selectorExpr.X))
-		return false, false
-	}
-
-	// check that the parameter type is exactly `*testing.T`
-	// TODO allow this to accept other param types for Fuzz/Benchmark tests (and maybe testing.TB)
-	// TODO maybe allow this case with `badFormat`?
-	if paramPackageIdent.Name != "testing" || selectorExpr.Sel.Name != "T" {
-		// slog.Debug("\tfunction has invalid param type", "name", name, "paramType", reflect.TypeOf(paramType))
+	kind, valid, badFormat := ClassifyFunction(funcDecl)
+	if !valid || kind != TestKindTest {
 		return false, false
 	}
-
-	slog.Debug("Found valid test case:", "name", name)
-
 	return true, badFormat
 }
 
@@ -216,6 +194,21 @@ func (tc *TestCase) NumLines() int {
 	return end.Line - start.Line + 1
 }
 
+// Return the name of the variable used to report failures and run subtests in the test case: the receiver of
+// a suite method, or the `*testing.T` (or `*testing.B`) parameter of other test functions.
+func (tc *TestCase) testerName() (string, error) {
+	if tc.funcDecl == nil {
+		return "", fmt.Errorf("cannot detect tester in test case without a function declaration")
+	}
+	if tc.Kind == TestKindSuite {
+		if name := receiverName(tc.funcDecl); name != "" {
+			return name, nil
+		}
+		return "", fmt.Errorf("suite method %q has an unnamed receiver", tc.TestName)
+	}
+	return asttools.GetParamNameByType(tc.funcDecl, &ast.StarExpr{X: asttools.NewSelectorExpr("testing", "T")}, &ast.StarExpr{X: asttools.NewSelectorExpr("testing", "B")})
+}
+
 // Get the AST file where the test case is defined
 func (tc *TestCase) GetFile() *ast.File { return tc.file }
 
@@ -246,6 +239,26 @@ func (tc *TestCase) ObjectOf(ident *ast.Ident) types.Object {
 	return typeInfo.ObjectOf(ident)
 }
 
+// Convenience method for getting the constant value of an expression within the current TestCase's project, like
+// the value of a literal or a named constant. Only literals are recognized if type information is not available.
+// Returns `nil` if the expression is not constant.
+func (tc *TestCase) ConstantOf(expr ast.Expr) constant.Value {
+	if expr == nil {
+		return nil
+	}
+	if typeInfo := tc.TypeInfo(); typeInfo != nil {
+		if tv, ok := typeInfo.Types[expr]; ok && tv.Value != nil {
+			return tv.Value
+		}
+	}
+	if lit, ok := expr.(*ast.BasicLit); ok {
+		if value := constant.MakeFromLiteral(lit.Value, lit.Kind, 0); value.Kind() != constant.Unknown {
+			return value
+		}
+	}
+	return nil
+}
+
 //
 // ========== Test Execution ==========
 //
@@ -306,19 +319,45 @@ func (ter *TestExecutionResult) UnmarshalJSON(data []byte) error {
 }
 
 // Execute a test based on the contents of its corresponding file in the file system using `go test`, and return the results.
+// Only regular tests and testify suite methods can be executed, since they're the only kinds that can be refactored.
 // Returns an error if the test fails for any reason.
-func (tc *TestCase) Execute() (TestExecutionResult, error) {
+// The test process is stopped when `ctx` is cancelled, in which case the test is reported as not run rather than failed.
+func (tc *TestCase) Execute(ctx context.Context) (TestExecutionResult, error) {
 	if tc.FilePath == "" || tc.TestName == "" {
 		return TestExecutionResultNotRun, fmt.Errorf("missing FilePath or TestName in TestCase: %v", tc)
 	}
 
+	if tc.Kind != TestKindTest && tc.Kind != TestKindSuite {
+		return TestExecutionResultNotRun, fmt.Errorf("cannot execute %s function %q (file %q)", tc.Kind, tc.TestName, tc.FilePath)
+	}
+	if tc.Kind == TestKindSuite && tc.SuiteRunner == "" {
+		return TestExecutionResultNotRun, fmt.Errorf("no test function runs suite %q (file %q)", tc.Suite, tc.FilePath)
+	}
+
 	slog.Debug("Executing test case", "file", tc.FilePath, "test", tc)
 
-	// Build the go test command
+	// Build the go test command, enabling any build tags required to include the test
+	tags, err := tc.executionTags()
+	if err != nil {
+		return TestExecutionResultNotRun, err
+	}
 	testPattern := fmt.Sprintf("^%s$", tc.TestName)
-	cmd := []string{"go", "test", "-run", testPattern, "-v"}
-	c := exec.Command(cmd[0], cmd[1:]...)
+	cmd := []string{"go", "test"}
+	if len(tags) > 0 {
+		cmd = append(cmd, "-tags="+strings.Join(tags, ","))
+	}
+	switch tc.Kind {
+	case TestKindSuite:
+		// Run the suite, but only the specified method of the suite
+		cmd = append(cmd, "-run", fmt.Sprintf("^%s$", tc.SuiteRunner), "-testify.m", testPattern, "-v")
+	default:
+		cmd = append(cmd, "-run", testPattern, "-v")
+	}
+	c := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
 	c.Dir = filepath.Dir(tc.FilePath) // Use the directory of the test file as the working directory
+	// Interrupt signals from the terminal are sent to the whole process group, so the test runs in its own group
+	// to avoid being killed before the parser decides what to do. The group is stopped when `ctx` is cancelled.
+	setProcessGroup(c)
 
 	// Execute the command and save the output
 	// FIXME maybe make this concurrent somehow?
@@ -326,6 +365,11 @@ func (tc *TestCase) Execute() (TestExecutionResult, error) {
 	output := string(outBytes)
 
 	if err != nil {
+		// Executions cut off by an interrupt didn't actually fail
+		if ctx.Err() != nil {
+			return TestExecutionResultNotRun, fmt.Errorf("test execution interrupted: %w", ctx.Err())
+		}
+
 		// Extract any relevant information from stderr
 		var stderr string = "[no stderr output]"
 		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
@@ -370,21 +414,63 @@ func (tc *TestCase) Execute() (TestExecutionResult, error) {
 
 // Return a string representation of the TestCase for logging and debugging purposes
 func (tc *TestCase) String() string {
-	return fmt.Sprintf("TestCase{Name: %s, Package: %s, FilePath: %s, Project: %s}", tc.TestName, tc.PackageName, tc.FilePath, tc.ProjectName)
+	return fmt.Sprintf("TestCase{Name: %s, Kind: %s, Package: %s, Variant: %s, FilePath: %s, Project: %s}", tc.TestName, tc.Kind, tc.PackageName, tc.PackageVariant, tc.FilePath, tc.ProjectName)
 }
 
 // Return the filepath where the test case's JSON representation should be saved, using the specified directory as a base if provided.
-// The returned path is formatted like `<project>/<project>_<package>_<testName>.json`.
+// The returned path is formatted like `<project>/<project>_<package>_<testName>.json`, where the test name of
+// a suite method is prefixed by the name of its suite (like `MySuite_TestFoo`) to avoid collisions between suites.
+// The full texts of Ginkgo specs are used as their names, replacing characters like spaces with underscores.
+// Different specs can have the same text (or texts that only differ in punctuation), so the names of Ginkgo specs are
+// followed by the file and line where they're defined (like `Calc_adds_calc_test_L12`).
 func (tc *TestCase) GetJSONFilePath(dir string) string {
-	return filepath.Join(dir, tc.ProjectName, fmt.Sprintf("%s_%s_%s.json", tc.ProjectName, tc.PackageName, tc.TestName))
+	name := tc.TestName
+	if tc.Kind == TestKindGinkgo {
+		name = strings.Map(func(r rune) rune {
+			if r == '-' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return '_'
+		}, name)
+		if pos := tc.specPosition(); pos.IsValid() {
+			name += fmt.Sprintf("_%s_L%d", strings.TrimSuffix(filepath.Base(pos.Filename), ".go"), pos.Line)
+		}
+	} else if tc.Suite != "" {
+		name = tc.Suite + "_" + name
+	}
+	return filepath.Join(dir, tc.ProjectName, fmt.Sprintf("%s_%s_%s.json", tc.ProjectName, tc.PackageName, name))
+}
+
+// Return the source position of the call that defines a Ginkgo spec, or an invalid position if it isn't known.
+func (tc *TestCase) specPosition() token.Position {
+	fset := tc.FileSet()
+	if fset == nil {
+		return token.Position{}
+	}
+	if tc.spec != nil && tc.spec.Call != nil {
+		return fset.Position(tc.spec.Call.Pos())
+	}
+	if tc.funcDecl != nil {
+		return fset.Position(tc.funcDecl.Pos())
+	}
+	return token.Position{}
 }
 
 // Helper struct for Marshaling JSON
 type testCaseJSON struct {
-	Name        string `json:"name"`
-	PackageName string `json:"package"`
-	FilePath    string `json:"filePath"`
-	ProjectName string `json:"project"`
+	Name           string   `json:"name"`
+	PackageName    string   `json:"package"`
+	PackageVariant string   `json:"packageVariant"`
+	FilePath       string   `json:"filePath"`
+	ProjectName    string   `json:"project"`
+	Kind           TestKind `json:"kind"`
+
+	BuildConstraint string `json:"buildConstraint"`
+
+	Suite       string `json:"suite,omitempty"`
+	SuiteRunner string `json:"suiteRunner,omitempty"`
+
+	SpecPath []string `json:"specPath,omitempty"`
 
 	FuncDecl string `json:"funcDecl"`
 	// Remaining syntax data is not marshaled
@@ -393,10 +479,19 @@ type testCaseJSON struct {
 // Marshal a TestCase for JSON output
 func (tc *TestCase) MarshalJSON() ([]byte, error) {
 	return json.Marshal(testCaseJSON{
-		Name:        tc.TestName,
-		PackageName: tc.PackageName,
-		FilePath:    tc.FilePath,
-		ProjectName: tc.ProjectName,
+		Name:           tc.TestName,
+		PackageName:    tc.PackageName,
+		PackageVariant: tc.PackageVariant,
+		FilePath:       tc.FilePath,
+		ProjectName:    tc.ProjectName,
+		Kind:           tc.Kind,
+
+		BuildConstraint: tc.BuildConstraint,
+
+		Suite:       tc.Suite,
+		SuiteRunner: tc.SuiteRunner,
+
+		SpecPath: tc.SpecPath,
 
 		FuncDecl: asttools.NodeToString(tc.funcDecl, tc.FileSet()),
 		// Remaining syntax data is not marshaled
@@ -425,10 +520,19 @@ func (tc *TestCase) UnmarshalJSON(data []byte) error {
 	}
 
 	*tc = TestCase{
-		TestName:    jsonData.Name,
-		PackageName: jsonData.PackageName,
-		FilePath:    jsonData.FilePath,
-		ProjectName: jsonData.ProjectName,
+		TestName:       jsonData.Name,
+		PackageName:    jsonData.PackageName,
+		PackageVariant: jsonData.PackageVariant,
+		FilePath:       jsonData.FilePath,
+		ProjectName:    jsonData.ProjectName,
+		Kind:           jsonData.Kind,
+
+		BuildConstraint: jsonData.BuildConstraint,
+
+		Suite:       jsonData.Suite,
+		SuiteRunner: jsonData.SuiteRunner,
+
+		SpecPath: jsonData.SpecPath,
 
 		funcDecl: funcDecl,
 		// Remaining syntax data cannot be recovered
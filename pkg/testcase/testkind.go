@@ -0,0 +1,264 @@
+package testcase
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Represents the kind of a function recognized by `go test`, which determines how it's validated and run.
+type TestKind int
+
+const (
+	TestKindTest      TestKind = iota // A regular test, like `func TestXxx(t *testing.T)`
+	TestKindBenchmark                 // A benchmark, like `func BenchmarkXxx(b *testing.B)`
+	TestKindFuzz                      // A fuzz test, like `func FuzzXxx(f *testing.F)`
+	TestKindExample                   // An example, like `func ExampleXxx()`, which is only run if it has an output comment
+	TestKindMain                      // The `func TestMain(m *testing.M)` function, which controls how a package's tests are run
+	TestKindSuite                     // A method of a testify suite, like `func (s *MySuite) TestXxx()` (see Suite)
+	TestKindGinkgo                    // A Ginkgo spec, i.e. an `It` or `DescribeTable` node (see GinkgoNode)
+)
+
+// Return every TestKind, in order.
+func AllTestKinds() []TestKind {
+	return []TestKind{TestKindTest, TestKindBenchmark, TestKindFuzz, TestKindExample, TestKindMain, TestKindSuite, TestKindGinkgo}
+}
+
+// Return the TestKind corresponding to the given string.
+func TestKindFromString(kind string) TestKind {
+	switch strings.ToLower(kind) {
+	case "benchmark":
+		return TestKindBenchmark
+	case "fuzz":
+		return TestKindFuzz
+	case "example":
+		return TestKindExample
+	case "main":
+		return TestKindMain
+	case "suite":
+		return TestKindSuite
+	case "ginkgo":
+		return TestKindGinkgo
+	case "test":
+		return TestKindTest
+	default:
+		slog.Warn("Unknown test kind", "kind", kind)
+		return TestKindTest
+	}
+}
+
+func (k TestKind) String() string {
+	switch k {
+	case TestKindTest:
+		return "test"
+	case TestKindBenchmark:
+		return "benchmark"
+	case TestKindFuzz:
+		return "fuzz"
+	case TestKindExample:
+		return "example"
+	case TestKindMain:
+		return "main"
+	case TestKindSuite:
+		return "suite"
+	case TestKindGinkgo:
+		return "ginkgo"
+	default:
+		return "unknown"
+	}
+}
+
+func (k TestKind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.String())
+}
+
+func (k *TestKind) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*k = TestKindFromString(str)
+	return nil
+}
+
+// Return the prefix that the names of functions of this kind start with.
+func (k TestKind) prefix() string {
+	switch k {
+	case TestKindBenchmark:
+		return "Benchmark"
+	case TestKindFuzz:
+		return "Fuzz"
+	case TestKindExample:
+		return "Example"
+	case TestKindMain:
+		return "TestMain"
+	default:
+		return "Test"
+	}
+}
+
+// Return the name of the type in the `testing` package that functions of this kind take a pointer to as their only
+// parameter, or an empty string if they have no parameters.
+func (k TestKind) paramType() string {
+	switch k {
+	case TestKindTest:
+		return "T"
+	case TestKindBenchmark:
+		return "B"
+	case TestKindFuzz:
+		return "F"
+	case TestKindMain:
+		return "M"
+	default:
+		return ""
+	}
+}
+
+// Determine whether the given function declaration is a valid test function of any kind, and which kind it is.
+// Returns the detected `kind` based on the function's name, `valid` indicating whether the function has the correct
+// signature for its kind, and `badFormat` indicating whether the function's name has an unusual (but acceptable) format.
+// `badFormat` is false if the function is not valid. See FindNearMiss for the reasons that functions aren't valid.
+// Functions are only run by `go test` if they're also declared in a test file (see IsTestFile).
+//
+// The function is validated using the following criteria:
+// - The function name starts with "Test", "Benchmark", "Fuzz", or "Example" (or is exactly "TestMain"),
+// not followed by a lowercase letter
+// - The function has `*testing.T`, `*testing.B`, `*testing.F`, or `*testing.M` (respectively) as its only formal parameter,
+// or no parameters for examples
+// - The function does not have any receiver (i.e., it is not a method)
+// - The function does not have any generic type parameters
+// - The function does not return any values
+func ClassifyFunction(funcDecl *ast.FuncDecl) (kind TestKind, valid bool, badFormat bool) {
+	kind, matched, reason, badFormat := classifyFunction(funcDecl)
+	if !matched || reason != NearMissNone {
+		return kind, false, false
+	}
+	slog.Debug("Found valid test function:", "name", funcDecl.Name.Name, "kind", kind)
+	return kind, true, badFormat
+}
+
+// Return whether the file is a test file, i.e. its name ends in "_test.go". Functions in other files are never run
+// by `go test`, even if they're valid test functions (e.g. `func ExampleConfig()` in a production package).
+func IsTestFile(file *ast.File, fset *token.FileSet) bool {
+	if file == nil || fset == nil {
+		return false
+	}
+	return strings.HasSuffix(fset.Position(file.FileStart).Filename, "_test.go")
+}
+
+// Classify a function based on its name and signature. Returns `matched` indicating whether the function looks like
+// a test function, along with its detected kind and the reason it isn't a valid test function (or NearMissNone if it is).
+// Functions are only matched if either their name or their signature is correct, since functions like `testHelper`
+// or `TestingHelper(t *testing.T, x int)` are usually helpers rather than broken tests.
+func classifyFunction(funcDecl *ast.FuncDecl) (kind TestKind, matched bool, reason NearMissReason, badFormat bool) {
+	if funcDecl == nil || funcDecl.Name == nil {
+		return TestKindTest, false, NearMissNone, false
+	}
+	name := funcDecl.Name.Name
+
+	// Determine the kind based on the name, checking `TestMain` before regular tests since it has the same prefix.
+	// Names with the prefix in the wrong case (like `testFoo`) are only matched if the signature is correct.
+	kind, matched = kindFromName(name)
+	if !matched {
+		for _, k := range []TestKind{TestKindTest, TestKindBenchmark, TestKindFuzz} {
+			if len(name) > len(k.prefix()) && strings.EqualFold(name[:len(k.prefix())], k.prefix()) && hasTestSignature(funcDecl, k) {
+				return k, true, NearMissLowercasePrefix, false
+			}
+		}
+		return TestKindTest, false, NearMissNone, false
+	}
+
+	// `go test` ignores functions whose prefix is followed by a lowercase letter (like `Testfoo`), but any other character
+	// is allowed. The letter after the prefix *should* be capitalized, so anything else is considered a bad format.
+	// Examples can also be named `Example` or `Example_suffix` to document the entire package.
+	if kind != TestKindMain {
+		rest := strings.TrimPrefix(name, kind.prefix())
+		first, _ := utf8.DecodeRuneInString(rest)
+		if unicode.IsLower(first) {
+			if !hasTestSignature(funcDecl, kind) {
+				return kind, false, NearMissNone, false
+			}
+			return kind, true, NearMissLowercaseName, false
+		}
+		if kind == TestKindExample {
+			badFormat = rest != "" && first != '_' && !unicode.IsUpper(first)
+		} else {
+			badFormat = !unicode.IsUpper(first)
+		}
+	}
+
+	// make sure the function has no receiver, type parameters, or return value
+	funcType := funcDecl.Type
+	switch {
+	case funcDecl.Recv != nil:
+		return kind, true, NearMissMethod, false
+	case funcType.TypeParams != nil:
+		return kind, true, NearMissTypeParams, false
+	case funcType.Results != nil:
+		return kind, true, NearMissResults, false
+	}
+
+	// make sure the function has exactly one parameter of the expected type, or none for examples
+	params := funcType.Params.List
+	numParams := 0
+	for _, param := range params {
+		numParams += max(len(param.Names), 1)
+	}
+	switch {
+	case kind == TestKindExample && numParams != 0, kind != TestKindExample && numParams != 1:
+		return kind, true, NearMissParamCount, false
+	case kind != TestKindExample && !isTestingPointer(params[0].Type, kind.paramType()):
+		return kind, true, NearMissParamType, false
+	}
+
+	return kind, true, NearMissNone, badFormat
+}
+
+// Return the kind of test function indicated by the function's name, and whether the name starts with any test prefix.
+func kindFromName(name string) (TestKind, bool) {
+	switch {
+	case name == "TestMain":
+		return TestKindMain, true
+	case strings.HasPrefix(name, "Test"):
+		return TestKindTest, true
+	case strings.HasPrefix(name, "Benchmark"):
+		return TestKindBenchmark, true
+	case strings.HasPrefix(name, "Fuzz"):
+		return TestKindFuzz, true
+	case strings.HasPrefix(name, "Example"):
+		return TestKindExample, true
+	default:
+		return TestKindTest, false
+	}
+}
+
+// Return whether the function has the correct signature for the specified kind, ignoring its name.
+func hasTestSignature(funcDecl *ast.FuncDecl, kind TestKind) bool {
+	funcType := funcDecl.Type
+	if funcDecl.Recv != nil || funcType.TypeParams != nil || funcType.Results != nil {
+		return false
+	}
+	params := funcType.Params.List
+	if kind == TestKindExample {
+		return len(params) == 0
+	}
+	return len(params) == 1 && len(params[0].Names) <= 1 && isTestingPointer(params[0].Type, kind.paramType())
+}
+
+// Return whether the expression is a pointer to the specified type in the `testing` package, like `*testing.T`.
+func isTestingPointer(expr ast.Expr, typeName string) bool {
+	starExpr, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	selectorExpr, ok := starExpr.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := selectorExpr.X.(*ast.Ident)
+	return ok && ident.Name == "testing" && selectorExpr.Sel.Name == typeName
+}
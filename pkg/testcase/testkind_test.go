@@ -0,0 +1,44 @@
+package testcase
+
+import "testing"
+
+func TestClassifyFunction(t *testing.T) {
+	funcs := loadFixtureFuncs(t, "kinds")
+
+	tests := []struct {
+		name      string
+		kind      TestKind
+		valid     bool
+		badFormat bool
+		testFile  bool
+	}{
+		{"TestAdd", TestKindTest, true, false, true},
+		{"Test_add", TestKindTest, true, true, true},
+		{"Test", TestKindTest, true, true, true},
+		{"TestMain", TestKindMain, true, false, true},
+		{"BenchmarkAdd", TestKindBenchmark, true, false, true},
+		{"Benchmark2", TestKindBenchmark, true, true, true},
+		{"FuzzAdd", TestKindFuzz, true, false, true},
+		{"ExampleAdd", TestKindExample, true, false, true},
+		{"Example", TestKindExample, true, false, true},
+		{"Example_second", TestKindExample, true, false, true},
+		{"helper", TestKindTest, false, false, true},
+		{"Testing", TestKindTest, false, false, true},
+		{"TestHelper", TestKindTest, true, false, false},
+		{"ExampleClient", TestKindExample, true, false, false},
+		{"Client.TestConnection", TestKindTest, false, false, false},
+		{"Add", TestKindTest, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fn := findFixtureFunc(t, funcs, tt.name)
+			kind, valid, badFormat := ClassifyFunction(fn.decl)
+			if kind != tt.kind || valid != tt.valid || badFormat != tt.badFormat {
+				t.Errorf("ClassifyFunction() = (%s, %v, %v), want (%s, %v, %v)", kind, valid, badFormat, tt.kind, tt.valid, tt.badFormat)
+			}
+			if got := IsTestFile(fn.file, fn.pkg.Fset); got != tt.testFile {
+				t.Errorf("IsTestFile() = %v, want %v", got, tt.testFile)
+			}
+		})
+	}
+}
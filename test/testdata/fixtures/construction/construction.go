@@ -0,0 +1,3 @@
+package construction
+
+func Sq(x int) int { return x * x }
@@ -0,0 +1,100 @@
+package construction
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testCase struct {
+	name string
+	in   int
+	want int
+}
+
+func testCases() []testCase {
+	return []testCase{
+		{"two", 2, 4},
+		{"three", 3, 9},
+	}
+}
+
+func buildCases() []testCase {
+	cases := []testCase{{"one", 1, 1}}
+	return cases
+}
+
+func TestHelperReturn(t *testing.T) {
+	for _, tt := range testCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			if Sq(tt.in) != tt.want {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestHelperAssign(t *testing.T) {
+	tests := buildCases()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Sq(tt.in) != tt.want {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func TestAppendLoop(t *testing.T) {
+	var cases []testCase
+	for i := 0; i < 5; i++ {
+		cases = append(cases, testCase{name: fmt.Sprint(i), in: i, want: i * i})
+	}
+	cases = append(cases, testCase{"ten", 10, 100}, testCase{"eleven", 11, 121})
+	for _, tt := range cases {
+		var got []int
+		got = append(got, Sq(tt.in))
+		if got[0] != tt.want {
+			t.Fail()
+		}
+	}
+}
+
+func TestLiteralPlusAppend(t *testing.T) {
+	cases := []*testCase{{"one", 1, 1}}
+	cases = append(cases, &testCase{"two", 2, 4})
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if Sq(tt.in) != tt.want {
+				t.Fail()
+			}
+		})
+	}
+}
+
+func appendHelper() []testCase {
+	var out []testCase
+	out = append(out, testCase{"h", 4, 16})
+	return out
+}
+
+func TestAppendHelper(t *testing.T) {
+	for _, tt := range appendHelper() {
+		if Sq(tt.in) != tt.want {
+			t.Fail()
+		}
+	}
+}
+
+func TestAppendResults(t *testing.T) {
+	tests := []testCase{{"one", 1, 1}, {"two", 2, 4}}
+	var got []testCase
+	for _, tt := range tests {
+		got = append(got, tt)
+	}
+	_ = got
+	for _, tt := range tests {
+		if Sq(tt.in) != tt.want {
+			t.Fail()
+		}
+	}
+}
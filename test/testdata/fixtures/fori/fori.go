@@ -0,0 +1,3 @@
+package fori
+
+func Add(a, b int) int { return a + b }
@@ -0,0 +1,80 @@
+package fori
+
+import "testing"
+
+func TestForLen(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b int
+		want int
+	}{
+		{"zero", 0, 0, 0},
+		{"one", 1, 0, 1},
+	}
+	for i := 0; i < len(tests); i++ {
+		tt := tests[i]
+		if Add(tt.a, tt.b) != tt.want {
+			t.Errorf("%s: wrong sum", tt.name)
+		}
+	}
+}
+
+func TestForPointer(t *testing.T) {
+	tests := []*struct {
+		name string
+		in   int
+	}{
+		{"a", 1},
+		{"b", 2},
+		{"c", 3},
+	}
+	for i := 0; i < len(tests); i++ {
+		tc := tests[i]
+		_ = Add(tc.in, 0)
+	}
+}
+
+var cases = [...]struct {
+	desc string
+	a, b int
+}{
+	{"x", 1, 2},
+	{"y", 3, 4},
+}
+
+func TestForBound(t *testing.T) {
+	n := 2
+	for i := 0; i < n; i += 1 {
+		if Add(cases[i].a, cases[i].b) == 0 {
+			t.Error(cases[i].desc)
+		}
+	}
+}
+
+func TestForSubtests(t *testing.T) {
+	tests := []struct {
+		name string
+		in   int
+	}{
+		{"a", 1},
+		{"b", 2},
+	}
+	for i := 0; i < len(tests); i++ {
+		t.Run(tests[i].name, func(t *testing.T) {
+			_ = Add(tests[i].in, 0)
+		})
+	}
+}
+
+func TestForNotTable(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		_ = Add(i, i)
+	}
+}
+
+func TestForDecreasing(t *testing.T) {
+	tests := []struct{ in int }{{1}, {2}}
+	for i := len(tests) - 1; i >= 0; i-- {
+		_ = Add(tests[i].in, 0)
+	}
+}
@@ -0,0 +1,13 @@
+module fixtures
+
+go 1.24
+
+require (
+	github.com/onsi/ginkgo/v2 v2.0.0
+	github.com/stretchr/testify v1.0.0
+)
+
+replace (
+	github.com/onsi/ginkgo/v2 => ./stubs/ginkgo
+	github.com/stretchr/testify => ./stubs/testify
+)
@@ -0,0 +1,14 @@
+package kinds
+
+import "testing"
+
+type Client struct{}
+
+// Production code can't contain tests, even if it has functions that look like them
+func (c *Client) TestConnection() error { return nil }
+
+func ExampleClient() {}
+
+func TestHelper(t *testing.T) {}
+
+func Add(a, b int) int { return a + b }
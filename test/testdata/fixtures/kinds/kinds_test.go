@@ -0,0 +1,34 @@
+package kinds
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) { os.Exit(m.Run()) }
+
+func TestAdd(t *testing.T) {}
+
+func Test_add(t *testing.T) {}
+
+func Test(t *testing.T) {}
+
+func BenchmarkAdd(b *testing.B) {}
+
+func Benchmark2(b *testing.B) {}
+
+func FuzzAdd(f *testing.F) {}
+
+func ExampleAdd() {
+	fmt.Println(Add(1, 2))
+	// Output: 3
+}
+
+func Example() {}
+
+func Example_second() {}
+
+func helper(t *testing.T) {}
+
+func Testing() {}
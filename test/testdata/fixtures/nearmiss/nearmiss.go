@@ -0,0 +1,10 @@
+package nearmiss
+
+import "testing"
+
+type Client struct{}
+
+// Functions outside test files are never near misses, even if they'd be near misses in a test file
+func (c *Client) TestConnection() error { return nil }
+
+func Testhelper(t *testing.T) {}
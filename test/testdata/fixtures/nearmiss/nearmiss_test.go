@@ -0,0 +1,46 @@
+package nearmiss
+
+import "testing"
+
+type mySuite struct{}
+
+func TestValid(t *testing.T) {}
+
+func Testlower(t *testing.T) {}
+
+func Benchmarkfoo(b *testing.B) {}
+
+func Exampleadd() {}
+
+func testLower(t *testing.T) {}
+
+func benchmarkLower(b *testing.B) {}
+
+func (s *mySuite) TestMethod(t *testing.T) {}
+
+func TestGeneric[T any](t *testing.T) {}
+
+func TestResults(t *testing.T) error { return nil }
+
+func TestNoParams() {}
+
+func TestTwoParams(t *testing.T, x int) {}
+
+func TestSharedParams(t, u *testing.T) {}
+
+func ExampleParams(t *testing.T) {}
+
+func TestInterface(t testing.TB) {}
+
+func TestWrongPointer(b *testing.B) {}
+
+func FuzzWrongPointer(t *testing.T) {}
+
+func TestMain(t *testing.T) {}
+
+// Functions that don't look like test functions at all
+func Testing() {}
+
+func testHelper(t *testing.T, x int) {}
+
+func setup() {}
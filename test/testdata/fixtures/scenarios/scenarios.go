@@ -0,0 +1,3 @@
+package scenarios
+
+func Div(a, b int) (int, error) { return a / b, nil }
@@ -0,0 +1,61 @@
+package scenarios
+
+import (
+	"fmt"
+	"testing"
+)
+
+const bigName = "big"
+
+type tcase struct {
+	name    string
+	a, b    int
+	want    float64
+	wantErr bool
+}
+
+func TestKeyed(t *testing.T) {
+	tests := []*tcase{
+		{name: "one", a: 1, b: 1, want: 1},
+		&tcase{name: bigName, a: 1 << 40, b: 2, want: 0.5, wantErr: true},
+		{name: fmt.Sprint("dyn"), a: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) { _, _ = Div(tt.a, tt.b) })
+	}
+}
+
+func TestPositional(t *testing.T) {
+	for _, tt := range []struct {
+		a, b int
+	}{{1, 2}, {-3, 4}} {
+		_, _ = Div(tt.a, tt.b)
+	}
+}
+
+func TestMap(t *testing.T) {
+	tests := map[string]bool{
+		"yes": true,
+		"no":  false,
+	}
+	for name, ok := range tests {
+		t.Run(name, func(t *testing.T) { _ = ok })
+	}
+}
+
+func TestMapOfStructs(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want int
+	}{
+		"empty": {"", 0},
+		"short": {in: "ab", want: 2},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if len(tt.in) != tt.want {
+				t.Error("wrong length")
+			}
+		})
+	}
+}
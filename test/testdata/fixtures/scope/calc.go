@@ -0,0 +1,3 @@
+package scope
+
+func Double(x int) int { return x * 2 }
@@ -0,0 +1,34 @@
+package scope
+
+import "testing"
+
+var fileCases = []doubleCase{
+	{"three", 3, 6},
+}
+
+func TestShared(t *testing.T) {
+	for _, tt := range sharedCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Double(tt.in); got != tt.want {
+				t.Errorf("got %d", got)
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	for i := 0; i < len(fileCases); i++ {
+		if got := Double(fileCases[i].in); got != fileCases[i].want {
+			t.Errorf("got %d", got)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	tests := []doubleCase{{"four", 4, 8}}
+	for _, tt := range tests {
+		if Double(tt.in) != tt.want {
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package scope
+
+type doubleCase struct {
+	name string
+	in   int
+	want int
+}
+
+var sharedCases = []doubleCase{
+	{"zero", 0, 0},
+	{"one", 1, 2},
+	{name: "neg", in: -2, want: -4},
+}
+
+// Exported for the external test package
+var ExportedCases = []doubleCase{
+	{"two", 2, 4},
+}
+
+func (c doubleCase) Name() string { return c.name }
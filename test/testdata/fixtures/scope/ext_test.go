@@ -0,0 +1,13 @@
+package scope_test
+
+import (
+	"testing"
+
+	"fixtures/scope"
+)
+
+func TestExternal(t *testing.T) {
+	for _, tt := range scope.ExportedCases {
+		t.Run(tt.Name(), func(t *testing.T) {})
+	}
+}
@@ -0,0 +1,7 @@
+package specs
+
+import g "github.com/onsi/ginkgo/v2"
+
+var _ = g.Describe("Alias", func() {
+	g.It("uses a named import", func() {})
+})
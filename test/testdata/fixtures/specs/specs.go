@@ -0,0 +1,3 @@
+package specs
+
+func Add(a, b int) int { return a + b }
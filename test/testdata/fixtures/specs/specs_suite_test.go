@@ -0,0 +1,11 @@
+package specs
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+func TestSpecs(t *testing.T) {
+	RunSpecs(t, "Specs Suite")
+}
@@ -0,0 +1,52 @@
+package specs
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("Calc", func() {
+	Context("when adding", func() {
+		It("adds positives", func() {})
+
+		FIt("adds negatives", func() {})
+
+		// Specs whose texts only differ in punctuation
+		It("adds (positives)", func() {})
+
+		It("adds [positives]", func() {})
+	})
+
+	When("pending", Pending, func() {
+		It("is skipped", func() {})
+	})
+
+	XDescribe("disabled", func() {
+		It("is disabled", func() {})
+	})
+
+	// Specs without a function aren't test cases
+	PIt("is not written yet")
+
+	DescribeTable("sums",
+		func(a, b, want int) {
+			_ = Add(a, b) == want
+		},
+		Entry("zeros", 0, 0, 0),
+		Entry("ones", 1, 1, 2),
+		Entry(fmt.Sprint("twos"), 2, 2, 4),
+	)
+
+	It("uses a named function", namedSpec)
+
+	Specify(fmt.Sprintf("computes %d", 1), func() {})
+})
+
+func namedSpec() {}
+
+func init() {
+	Describe("Init", func() {
+		It("is defined in init", func() {})
+	})
+}
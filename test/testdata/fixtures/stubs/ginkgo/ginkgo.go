@@ -0,0 +1,27 @@
+// Stub of the Ginkgo package, which only declares what the fixtures use so they type-check.
+package ginkgo
+
+import "testing"
+
+type PendingDecorator struct{}
+
+var Pending = PendingDecorator{}
+
+type TableEntry struct{}
+
+func RunSpecs(t *testing.T, description string, args ...any) bool { return true }
+
+func Describe(text string, args ...any) bool  { return true }
+func FDescribe(text string, args ...any) bool { return true }
+func PDescribe(text string, args ...any) bool { return true }
+func XDescribe(text string, args ...any) bool { return true }
+func Context(text string, args ...any) bool   { return true }
+func When(text string, args ...any) bool      { return true }
+
+func It(text string, args ...any) bool      { return true }
+func FIt(text string, args ...any) bool     { return true }
+func PIt(text string, args ...any) bool     { return true }
+func Specify(text string, args ...any) bool { return true }
+
+func DescribeTable(text string, args ...any) bool   { return true }
+func Entry(description any, args ...any) TableEntry { return TableEntry{} }
@@ -0,0 +1,19 @@
+// Stub of testify's suite package, which only declares what the fixtures use so they type-check.
+package suite
+
+import "testing"
+
+type TestingSuite interface {
+	T() *testing.T
+	SetT(*testing.T)
+	SetS(TestingSuite)
+}
+
+type Suite struct{ t *testing.T }
+
+func (s *Suite) T() *testing.T                  { return s.t }
+func (s *Suite) SetT(t *testing.T)              { s.t = t }
+func (s *Suite) SetS(TestingSuite)              {}
+func (s *Suite) Run(name string, f func()) bool { return true }
+
+func Run(t *testing.T, s TestingSuite) {}
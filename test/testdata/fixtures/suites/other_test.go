@@ -0,0 +1,20 @@
+package suites
+
+import (
+	"testing"
+
+	ts "github.com/stretchr/testify/suite"
+)
+
+// Methods can be declared in a different file from the suite type
+func (s *CalcSuite) TestOtherFile() {}
+
+// The suite package can be imported using a different name, and the suite instance can be any expression
+type AliasSuite struct{ *ts.Suite }
+
+func (a *AliasSuite) TestAlias() {}
+
+func TestAliasSuite(t *testing.T) {
+	s := &AliasSuite{Suite: new(ts.Suite)}
+	ts.Run(t, s)
+}
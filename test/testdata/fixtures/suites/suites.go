@@ -0,0 +1,3 @@
+package suites
+
+func Add(a, b int) int { return a + b }
@@ -0,0 +1,43 @@
+package suites
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CalcSuite struct {
+	suite.Suite
+	base int
+}
+
+func (s *CalcSuite) TestAdd() {}
+
+func (s CalcSuite) TestValueReceiver() {}
+
+func (s *CalcSuite) TestWithParam(x int) {}
+
+func (s *CalcSuite) TestWithResult() error { return nil }
+
+func (s *CalcSuite) helper() {}
+
+func TestCalcSuite(t *testing.T) {
+	suite.Run(t, new(CalcSuite))
+}
+
+// Embeds a suite type from the same package instead of `suite.Suite` itself
+type EmbeddedSuite struct{ CalcSuite }
+
+func (s *EmbeddedSuite) TestEmbedded() {}
+
+func TestEmbeddedSuite(t *testing.T) { suite.Run(t, &EmbeddedSuite{}) }
+
+// No test function runs this suite
+type OrphanSuite struct{ suite.Suite }
+
+func (s *OrphanSuite) TestOrphan() {}
+
+// Not a suite, since it doesn't embed `suite.Suite`
+type NotSuite struct{}
+
+func (n *NotSuite) TestNotSuite() {}
@@ -1,4 +1,66 @@
 // Place for test data and test helper code
 package testdata
 
-// TODO: Add test data files here
+import (
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// The information loaded for fixture packages, which matches what the parser loads for every visited package
+const loadMode = packages.LoadAllSyntax | packages.NeedForTest | packages.NeedModule
+
+// Return the absolute path of the `fixtures` module, which contains small example projects used as test inputs.
+// The module has its own `go.mod`, so its packages (and the stub versions of the libraries they import) aren't
+// part of this module.
+func FixturesDir(t testing.TB) string {
+	t.Helper()
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("cannot determine the location of the test data directory")
+	}
+	return filepath.Join(filepath.Dir(file), "fixtures")
+}
+
+// Load the package in the specified directory of the `fixtures` module, including its test files.
+// Returns every variant of the package that would be visited by the parser, which is the variant including the test
+// files if there is one (instead of the plain package), and the external test package if there is one.
+// The test fails if the package can't be loaded or has any errors.
+func LoadFixture(t testing.TB, dir string) []*packages.Package {
+	t.Helper()
+	cfg := &packages.Config{
+		Mode:  loadMode,
+		Dir:   FixturesDir(t),
+		Tests: true,
+	}
+	pkgs, err := packages.Load(cfg, "./"+filepath.ToSlash(dir))
+	if err != nil {
+		t.Fatalf("loading fixture %q: %v", dir, err)
+	}
+
+	var variants []*packages.Package
+	for _, pkg := range pkgs {
+		// Skip the generated test executable, whose errors are only about the signatures of test functions (which are
+		// wrong on purpose in some fixtures), as well as plain packages that are also loaded with their test files
+		if strings.HasSuffix(pkg.PkgPath, ".test") {
+			continue
+		}
+		if pkg.ID == pkg.PkgPath && slices.ContainsFunc(pkgs, func(other *packages.Package) bool {
+			return other != pkg && other.PkgPath == pkg.PkgPath
+		}) {
+			continue
+		}
+		for _, e := range pkg.Errors {
+			t.Errorf("error in fixture package %s: %v", pkg.ID, e)
+		}
+		variants = append(variants, pkg)
+	}
+	if len(variants) == 0 {
+		t.Fatalf("no packages found for fixture %q", dir)
+	}
+	return variants
+}